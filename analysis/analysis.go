@@ -0,0 +1,263 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package analysis implements language-aware text analysis for summaries,
+// slugs, keywords and search terms.
+package analysis
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Token is a normalized term with its byte offsets in the original text.
+type Token struct {
+	Text       string
+	Start, End int
+}
+
+// Tokenizer splits text of a specific locale into tokens.
+type Tokenizer interface {
+	Tokenize(text string) []Token
+	// IsStopWord reports whether normalized term carries no meaning on its own.
+	IsStopWord(term string) bool
+}
+
+var tokenizers = map[string]Tokenizer{
+	"zh-CN": cjkTokenizer{},
+	"zh-TW": cjkTokenizer{},
+	"ja-JP": cjkTokenizer{},
+	"ru-RU": cyrillicTokenizer{},
+}
+
+var defaultTokenizer Tokenizer = wordTokenizer{}
+
+// Register sets tokenizer for given locale.
+func Register(lang string, t Tokenizer) {
+	tokenizers[lang] = t
+}
+
+// ForLocale returns tokenizer of given locale or the default one.
+func ForLocale(lang string) Tokenizer {
+	if t, ok := tokenizers[lang]; ok {
+		return t
+	}
+	return defaultTokenizer
+}
+
+// Terms returns normalized terms of text in given locale.
+func Terms(lang, text string) []string {
+	tokens := ForLocale(lang).Tokenize(text)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Text
+	}
+	return terms
+}
+
+// unigramTokenizer is implemented by tokenizers whose terms span several
+// characters, to index the characters too.
+type unigramTokenizer interface {
+	Unigrams(text string) []Token
+}
+
+// IndexTerms returns terms of text to be indexed for search in given locale:
+// terms of Terms and, for locales segmented into bigrams, single characters,
+// so that one-character queries match as well.
+func IndexTerms(lang, text string) []string {
+	terms := Terms(lang, text)
+	if t, ok := ForLocale(lang).(unigramTokenizer); ok {
+		for _, tok := range t.Unigrams(text) {
+			terms = append(terms, tok.Text)
+		}
+	}
+	return terms
+}
+
+var (
+	reScriptStyle = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	reHTMLTag     = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// PlainText strips tags from rendered HTML and collapses white spaces.
+func PlainText(s string) string {
+	s = reScriptStyle.ReplaceAllString(s, " ")
+	s = reHTMLTag.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+// Summary truncates text to at most max runes without breaking a word,
+// and appends an ellipsis when text has been truncated.
+func Summary(lang, text string, max int) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+
+	// Byte offset of the max-th rune.
+	limit := len(text)
+	n := 0
+	for i := range text {
+		if n == max {
+			limit = i
+			break
+		}
+		n++
+	}
+
+	// Look a bit past the limit to know whether the last word is complete.
+	window := text
+	if len(window) > limit+64 {
+		window = window[:limit+64]
+	}
+
+	cut := 0
+	for _, t := range ForLocale(lang).Tokenize(window) {
+		if t.End <= limit && t.End > cut {
+			cut = t.End
+		}
+	}
+	// Word is longer than the limit.
+	if cut == 0 {
+		cut = limit
+	}
+
+	return strings.TrimRightFunc(text[:cut], func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+type keyword struct {
+	term  string
+	count int
+}
+
+// Keywords returns at most n most frequent meaningful terms of text.
+func Keywords(lang, text string, n int) []string {
+	t := ForLocale(lang)
+	counts := make(map[string]int)
+	for _, tok := range t.Tokenize(text) {
+		if t.IsStopWord(tok.Text) || !isKeyword(tok.Text) {
+			continue
+		}
+		counts[tok.Text]++
+	}
+
+	list := make([]keyword, 0, len(counts))
+	for term, count := range counts {
+		list = append(list, keyword{term, count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].count == list[j].count {
+			return list[i].term < list[j].term
+		}
+		return list[i].count > list[j].count
+	})
+
+	if len(list) > n {
+		list = list[:n]
+	}
+	keywords := make([]string, len(list))
+	for i, k := range list {
+		keywords[i] = k.term
+	}
+	return keywords
+}
+
+// isKeyword filters out numbers and single Latin or Cyrillic letters.
+func isKeyword(term string) bool {
+	letters := 0
+	for _, r := range term {
+		if isCJK(r) {
+			return true
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters > 1
+}
+
+// Slug returns an URL fragment safe identifier of text. Accents of Latin letters
+// are dropped and Cyrillic is transliterated by conventions of lang, other
+// non-Latin scripts are replaced by a stable hash of the original text.
+func Slug(lang, text string) string {
+	var buf strings.Builder
+	dash := false
+	hashed := false
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		latin, ok := transliterate(lang, r)
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			buf.WriteRune(r)
+			dash = false
+		case ok:
+			buf.WriteString(latin)
+			dash = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			hashed = true
+			fallthrough
+		default:
+			if !dash && buf.Len() > 0 {
+				buf.WriteByte('-')
+				dash = true
+			}
+		}
+	}
+
+	slug := strings.Trim(buf.String(), "-")
+	if hashed || len(slug) == 0 {
+		h := fnv.New32a()
+		h.Write([]byte(strings.TrimSpace(text)))
+		if len(slug) == 0 {
+			slug = "section"
+		}
+		slug = fmt.Sprintf("%s-%08x", slug, h.Sum32())
+	}
+	return slug
+}
+
+// transliterate returns Latin spelling of letter r in given locale.
+func transliterate(lang string, r rune) (string, bool) {
+	if latin, ok := localeTranslit[lang][r]; ok {
+		return latin, true
+	}
+	if latin, ok := translit[r]; ok {
+		return latin, true
+	}
+	if !unicode.Is(unicode.Latin, r) {
+		return "", false
+	}
+
+	// Letters with accents are decomposed into base letters and marks.
+	var buf strings.Builder
+	for _, c := range norm.NFD.String(string(r)) {
+		if unicode.Is(unicode.Mn, c) {
+			continue
+		}
+		if c >= utf8.RuneSelf {
+			return "", false
+		}
+		buf.WriteRune(c)
+	}
+	return buf.String(), buf.Len() > 0
+}
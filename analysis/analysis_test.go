@@ -0,0 +1,151 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package analysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		html, want string
+	}{
+		{"<p>Hello <b>beego</b></p>", "Hello beego"},
+		{"<style>p{}</style><p>a &amp; b</p>\n<script>x()</script>", "a & b"},
+		{"<h1>路由</h1>\n\n<p>设置</p>", "路由 设置"},
+	}
+	for _, test := range tests {
+		if got := PlainText(test.html); got != test.want {
+			t.Errorf("PlainText(%q) = %q, want %q", test.html, got, test.want)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		lang, text string
+		max        int
+		want       string
+	}{
+		{"en-US", "short text", 20, "short text"},
+		{"en-US", "  exactly ten  ", 11, "exactly ten"},
+		{"en-US", "beego is a web framework", 13, "beego is a…"},
+		{"en-US", "beego is a web framework", 14, "beego is a web…"},
+		{"en-US", "beego, the framework", 7, "beego…"},
+		{"en-US", "supercalifragilistic word", 5, "super…"},
+		{"ru-RU", "Быстрый старт с beego", 10, "Быстрый…"},
+		{"zh-CN", "从 beego 1.2 版本开始支持", 14, "从 beego 1.2 版本…"},
+	}
+	for _, test := range tests {
+		if got := Summary(test.lang, test.text, test.max); got != test.want {
+			t.Errorf("Summary(%q, %d) = %q, want %q", test.text, test.max, got, test.want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		lang, text, want string
+	}{
+		{"en-US", "Basic Router", "basic-router"},
+		{"en-US", "  What's new in 1.4?  ", "what-s-new-in-1-4"},
+		{"en-US", "Café Crème", "cafe-creme"},
+		{"en-US", "Straße", "strasse"},
+		{"de-DE", "Über uns", "ueber-uns"},
+		{"en-US", "Über uns", "uber-uns"},
+		{"ru-RU", "Быстрый старт", "bystryi-start"},
+		{"ru-RU", "Ёлка", "elka"},
+		{"ru-RU", "Гриб", "grib"},
+		{"uk-UA", "Гриб", "hryb"},
+		{"zh-CN", "路由", "section-" + fnvHex("路由")},
+		{"zh-CN", "beego 路由", "beego-" + fnvHex("beego 路由")},
+		{"en-US", "!!!", "section-" + fnvHex("!!!")},
+	}
+	for _, test := range tests {
+		if got := Slug(test.lang, test.text); got != test.want {
+			t.Errorf("Slug(%q, %q) = %q, want %q", test.lang, test.text, got, test.want)
+		}
+	}
+
+	if Slug("zh-CN", "路由") == Slug("zh-CN", "模板") {
+		t.Error("slugs of different CJK texts are the same")
+	}
+}
+
+func fnvHex(text string) string {
+	slug := Slug("zh-CN", text)
+	return slug[strings.LastIndex(slug, "-")+1:]
+}
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		lang, text string
+		n          int
+		want       []string
+	}{
+		{"en-US", "The router routes requests. A router is fast, the router is simple.", 2, []string{"router", "fast"}},
+		{"en-US", "a 1 2 3 x y go go", 5, []string{"go"}},
+		{"ru-RU", "Роутер и роутер, это роутер для запросов", 2, []string{"роутер", "запросов"}},
+		{"zh-CN", "路由设置，路由的使用", 2, []string{"路由", "由设"}},
+	}
+	for _, test := range tests {
+		if got := Keywords(test.lang, test.text, test.n); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Keywords(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestTokenizers(t *testing.T) {
+	tests := []struct {
+		lang, text string
+		want       []Token
+	}{
+		{"en-US", "Hello, beego!", []Token{{"hello", 0, 5}, {"beego", 7, 12}}},
+		{"en-US", "go路由", []Token{{"go", 0, 2}, {"路", 2, 5}, {"由", 5, 8}}},
+		{"zh-CN", "路由设置", []Token{{"路由", 0, 6}, {"由设", 3, 9}, {"设置", 6, 12}}},
+		{"zh-CN", "用 beego", []Token{{"用", 0, 3}, {"beego", 4, 9}}},
+		{"ja-JP", "ルーター", []Token{{"ルー", 0, 6}, {"ータ", 3, 9}, {"ター", 6, 12}}},
+		{"ru-RU", "Ёлка и ёж", []Token{{"елка", 0, 8}, {"и", 9, 11}, {"еж", 12, 16}}},
+	}
+	for _, test := range tests {
+		if got := ForLocale(test.lang).Tokenize(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Tokenize(%q, %q) = %v, want %v", test.lang, test.text, got, test.want)
+		}
+	}
+}
+
+func TestIndexTerms(t *testing.T) {
+	tests := []struct {
+		lang, text string
+		want       []string
+	}{
+		{"en-US", "Router", []string{"router"}},
+		{"zh-CN", "路由", []string{"路由", "路", "由"}},
+		{"zh-CN", "路", []string{"路"}},
+		{"zh-CN", "用 go", []string{"用", "go"}},
+	}
+	for _, test := range tests {
+		if got := IndexTerms(test.lang, test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("IndexTerms(%q, %q) = %q, want %q", test.lang, test.text, got, test.want)
+		}
+	}
+
+	// One-character queries match the characters indexed.
+	if got := Terms("zh-CN", "路"); !reflect.DeepEqual(got, []string{"路"}) {
+		t.Errorf("Terms(%q) = %q", "路", got)
+	}
+}
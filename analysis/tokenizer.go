@@ -0,0 +1,185 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package analysis
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func isCJK(r rune) bool {
+	// Prolonged sound and iteration marks are of common script.
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r == 'ー' || r == '々'
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// scan calls fn for every run of word runes in text,
+// CJK runs are reported separately from other words.
+func scan(text string, fn func(word string, start int, cjk bool)) {
+	start := -1
+	cjk := false
+	for i, r := range text {
+		isWord := isWordRune(r)
+		if start > -1 && (!isWord || isCJK(r) != cjk) {
+			fn(text[start:i], start, cjk)
+			start = -1
+		}
+		if isWord && start == -1 {
+			start = i
+			cjk = isCJK(r)
+		}
+	}
+	if start > -1 {
+		fn(text[start:], start, cjk)
+	}
+}
+
+// wordTokenizer splits text on everything but letters and digits,
+// CJK characters are emitted one by one.
+type wordTokenizer struct{}
+
+func (wordTokenizer) Tokenize(text string) []Token {
+	tokens := make([]Token, 0, len(text)/5)
+	scan(text, func(word string, start int, cjk bool) {
+		if !cjk {
+			tokens = append(tokens, Token{strings.ToLower(word), start, start + len(word)})
+			return
+		}
+		for i, r := range word {
+			tokens = append(tokens, Token{string(r), start + i, start + i + utf8.RuneLen(r)})
+		}
+	})
+	return tokens
+}
+
+func (wordTokenizer) IsStopWord(term string) bool {
+	return englishStopWords[term]
+}
+
+// cjkTokenizer segments runs of CJK characters into overlapping bigrams,
+// which needs no dictionary and works well for both matching and keywords.
+type cjkTokenizer struct{}
+
+func (cjkTokenizer) Tokenize(text string) []Token {
+	tokens := make([]Token, 0, len(text)/3)
+	scan(text, func(word string, start int, cjk bool) {
+		if !cjk {
+			tokens = append(tokens, Token{strings.ToLower(word), start, start + len(word)})
+			return
+		}
+
+		offsets := make([]int, 0, len(word)/3+1)
+		for i := range word {
+			offsets = append(offsets, i)
+		}
+		offsets = append(offsets, len(word))
+
+		if len(offsets) == 2 {
+			tokens = append(tokens, Token{word, start, start + len(word)})
+			return
+		}
+		for i := 0; i+2 < len(offsets); i++ {
+			tokens = append(tokens, Token{word[offsets[i]:offsets[i+2]], start + offsets[i], start + offsets[i+2]})
+		}
+	})
+	return tokens
+}
+
+// Unigrams returns characters of runs of CJK characters that Tokenize
+// splits into bigrams, single characters are tokens of their own already.
+func (cjkTokenizer) Unigrams(text string) []Token {
+	var tokens []Token
+	scan(text, func(word string, start int, cjk bool) {
+		if !cjk || utf8.RuneCountInString(word) < 2 {
+			return
+		}
+		for i, r := range word {
+			tokens = append(tokens, Token{string(r), start + i, start + i + utf8.RuneLen(r)})
+		}
+	})
+	return tokens
+}
+
+func (cjkTokenizer) IsStopWord(term string) bool {
+	if chineseStopWords[term] {
+		return true
+	}
+	// Bigrams containing a function character are poor keywords.
+	for _, r := range term {
+		if chineseStopWords[string(r)] {
+			return true
+		}
+	}
+	return englishStopWords[term]
+}
+
+// cyrillicTokenizer splits words like wordTokenizer and normalizes 'ё' to 'е'.
+type cyrillicTokenizer struct{}
+
+func (cyrillicTokenizer) Tokenize(text string) []Token {
+	tokens := wordTokenizer{}.Tokenize(text)
+	for i := range tokens {
+		tokens[i].Text = strings.Replace(tokens[i].Text, "ё", "е", -1)
+	}
+	return tokens
+}
+
+func (cyrillicTokenizer) IsStopWord(term string) bool {
+	return russianStopWords[term] || englishStopWords[term]
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+var englishStopWords = wordSet(`a an and are as at be but by can do for from has have how if in
+	into is it its not of on or so such that the their then there these this to was we
+	were what when which will with you your`)
+
+var russianStopWords = wordSet(`а без более бы был была были было быть в вам вас весь во вот все
+	всего вы где да для до его ее если есть еще же за здесь и из или им их к как ко когда
+	кто ли либо мне может мы на над надо не нее нет ни них но ну о об однако он она они оно
+	от очень по под при с со так также такой там те тем то того тоже той только том ты у уже
+	чем что чтобы эта эти это я`)
+
+var chineseStopWords = wordSet(`的 了 和 是 在 也 就 都 而 及 与 或 个 之 这 那 有 被 把 让 从 到 对 为 中
+	我 你 他 她 它 们 等 吗 呢 吧 啊 如果 可以 我们 你们 一个 没有 什么 使用 进行`)
+
+// translit maps Cyrillic letters, and Latin ones that do not decompose into a letter
+// and accents, to Latin. Cyrillic follows the ICAO transliteration.
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu",
+	'я': "ia", 'і': "i", 'ї': "i", 'є': "ie", 'ґ': "g",
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th",
+}
+
+// localeTranslit overrides translit by conventions of locales.
+var localeTranslit = map[string]map[rune]string{
+	// Ukrainian national transliteration.
+	"uk-UA": {'г': "h", 'и': "y", 'х': "kh", 'й': "i"},
+	"de-DE": {'ä': "ae", 'ö': "oe", 'ü': "ue"},
+}
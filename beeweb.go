@@ -16,6 +16,8 @@
 package main
 
 import (
+	"expvar"
+	"fmt"
 	"os"
 
 	"github.com/astaxie/beego"
@@ -45,34 +47,115 @@ func initialize() {
 	routers.InitApp()
 }
 
+// runCommand runs maintenance command given in command line arguments,
+// it returns false when there is no command and the server should start.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "snapshots":
+		versions, err := models.Snapshots()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		cur := models.CurrentSnapshot()
+		for _, v := range versions {
+			if v == cur {
+				fmt.Println("*", v)
+			} else {
+				fmt.Println(" ", v)
+			}
+		}
+	case "rollback":
+		var version string
+		if len(args) > 1 {
+			version = args[1]
+		}
+		if err := models.Rollback(version); err != nil {
+			fmt.Fprintln(os.Stderr, "rollback:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Current snapshot:", models.CurrentSnapshot())
+	case "translations":
+		report, err := models.LoadTranslations()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, page := range report.Pages {
+			for _, t := range page.Translations {
+				if t.Status != models.TranslationOK {
+					fmt.Printf("%-8s %-6s %s\n", t.Status, t.Lang, page.Link)
+				}
+			}
+		}
+		for _, lang := range report.Langs {
+			fmt.Printf("%s: %d of %d documents missing, %d outdated\n",
+				lang, report.Missing[lang], len(report.Pages), report.Stale[lang])
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "usage: beeweb [snapshots | rollback [version] | translations]")
+		os.Exit(2)
+	}
+	return true
+}
+
 func main() {
+	if runCommand(os.Args[1:]) {
+		return
+	}
 
 	initialize()
 
 	beego.Info(beego.BConfig.AppName, APP_VER)
 
 	beego.InsertFilter("/docs/images/:all", beego.BeforeRouter, routers.DocsStatic)
+	beego.InsertFilter("/:lang/docs/images/:all", beego.BeforeRouter, routers.DocsStatic)
+	beego.InsertFilter("/products/images/:all", beego.BeforeRouter, routers.ProductsStatic)
 
 	if !routers.IsPro {
 		beego.SetStaticPath("/static_source", "static_source")
 		beego.BConfig.WebConfig.DirectoryIndex = true
 	}
 
-	beego.SetStaticPath("/products/images", "products/images/")
-
-	// Register routers.
-	beego.Router("/", &routers.HomeRouter{})
-	beego.Router("/community", &routers.CommunityRouter{})
-	beego.Router("/quickstart", &routers.QuickStartRouter{})
-	beego.Router("/video", &routers.VideoRouter{})
-	beego.Router("/products", &routers.ProductsRouter{})
-	beego.Router("/team", &routers.PageRouter{})
-	beego.Router("/about", &routers.AboutRouter{})
-	beego.Router("/donate", &routers.DonateRouter{})
-	beego.Router("/docs/", &routers.DocsRouter{})
-	beego.Router("/docs/*", &routers.DocsRouter{})
-	beego.Router("/blog", &routers.BlogRouter{})
-	beego.Router("/blog/*", &routers.BlogRouter{})
+	// Webhooks verify signature of raw request body.
+	beego.BConfig.CopyRequestBody = true
+
+	if beego.AppConfig.DefaultBool("app::expvar", false) {
+		beego.Handler("/debug/vars", expvar.Handler())
+	}
+
+	// Register routers, pages are served under prefix of their language.
+	routers.Register("/", &routers.HomeRouter{})
+	routers.Register("/community", &routers.CommunityRouter{})
+	routers.Register("/quickstart", &routers.QuickStartRouter{})
+	routers.Register("/video", &routers.VideoRouter{})
+	routers.Register("/products", &routers.ProductsRouter{})
+	routers.Register("/team", &routers.PageRouter{})
+	routers.Register("/about", &routers.AboutRouter{})
+	routers.Register("/donate", &routers.DonateRouter{})
+	routers.Register("/docs/changes.atom", &routers.FeedRouter{}, "get:DocChanges")
+	routers.Register("/docs/", &routers.DocsRouter{})
+	routers.Register("/docs/*", &routers.DocsRouter{})
+	routers.Register("/blog", &routers.BlogRouter{}, "get:Index")
+	routers.Register("/blog/feed.atom", &routers.FeedRouter{}, "get:BlogAtom")
+	routers.Register("/blog/feed.rss", &routers.FeedRouter{}, "get:BlogRSS")
+	routers.Register("/blog/tag/:tag", &routers.BlogRouter{}, "get:Tag")
+	routers.Register("/blog/author/:name", &routers.BlogRouter{}, "get:Author")
+	routers.Register("/blog/*", &routers.BlogRouter{})
+	routers.Register("/search", &routers.SearchRouter{})
+	beego.Router("/api/search", &routers.SearchAPIRouter{})
+	routers.Register("/translations", &routers.TranslationsRouter{})
+	beego.Router("/hooks/content", &routers.HookRouter{})
+	beego.Router("/sitemap.xml", &routers.SitemapRouter{}, "get:Index")
+	beego.Router("/:lang/sitemap.xml", &routers.SitemapRouter{}, "get:Lang")
+	beego.Router("/robots.txt", &routers.SitemapRouter{}, "get:Robots")
+	beego.Router("/highlight.css", &routers.HighlightRouter{})
+
+	beego.ErrorController(&routers.ErrorRouter{})
 
 	// Register template functions.
 	beego.AddFuncMap("i18n", i18n.Tr)
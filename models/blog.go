@@ -0,0 +1,127 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Post is a blog post, its title, date, authors, tags and draft state
+// come from front matter of the document.
+type Post struct {
+	*DocNode
+	Slug string
+
+	// Prev is the newer post and Next the older one.
+	Prev, Next *Post
+}
+
+// Excerpt returns summary of post in its front matter, its description,
+// or summary of its content.
+func (p *Post) Excerpt() string {
+	if len(p.Abstract) > 0 {
+		return p.Abstract
+	}
+	if len(p.Description) > 0 {
+		return p.Description
+	}
+	return p.Summary
+}
+
+// HasTag returns true if post is tagged by given tag, ignoring case.
+func (p *Post) HasTag(tag string) bool {
+	return containsFold(p.Tags, tag)
+}
+
+// HasAuthor returns true if post is written by given author, ignoring case.
+func (p *Post) HasAuthor(name string) bool {
+	return containsFold(p.Authors, name)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildPosts returns posts of every language of roots, newest first.
+func buildPosts(roots map[string]*DocRoot) map[string][]*Post {
+	posts := make(map[string][]*Post, len(roots))
+	for lang, root := range roots {
+		list := make([]*Post, 0, len(root.pages))
+		for name, node := range root.pages {
+			if !node.HasContent() {
+				continue
+			}
+			list = append(list, &Post{DocNode: node, Slug: name})
+		}
+
+		sort.Slice(list, func(i, j int) bool {
+			if !list[i].Date.Equal(list[j].Date) {
+				return list[i].Date.After(list[j].Date)
+			}
+			return list[i].Slug < list[j].Slug
+		})
+		for i, p := range list {
+			if i > 0 {
+				p.Prev = list[i-1]
+			}
+			if i < len(list)-1 {
+				p.Next = list[i+1]
+			}
+		}
+		posts[lang] = list
+	}
+	return posts
+}
+
+// GetPosts returns blog posts of given language version, newest first.
+func GetPosts(lang string) []*Post {
+	return CurrentStore().posts[lang]
+}
+
+// GetPost returns blog post by given slug and language version.
+func GetPost(slug, lang string) *Post {
+	for _, p := range CurrentStore().posts[lang] {
+		if p.Slug == slug {
+			return p
+		}
+	}
+	return nil
+}
+
+// GetPostsByTag returns blog posts of given language version tagged by tag.
+func GetPostsByTag(tag, lang string) []*Post {
+	return filterPosts(GetPosts(lang), func(p *Post) bool { return p.HasTag(tag) })
+}
+
+// GetPostsByAuthor returns blog posts of given language version written by name.
+func GetPostsByAuthor(name, lang string) []*Post {
+	return filterPosts(GetPosts(lang), func(p *Post) bool { return p.HasAuthor(name) })
+}
+
+func filterPosts(posts []*Post, fn func(*Post) bool) []*Post {
+	var list []*Post
+	for _, p := range posts {
+		if fn(p) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
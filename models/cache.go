@@ -0,0 +1,126 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"container/list"
+	"expvar"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/astaxie/beego"
+)
+
+var renderCacheStats = expvar.NewMap("rendercache")
+
+// renderCache keeps rendered documents, least recently used ones are
+// dropped when total size of their HTML exceeds the limit.
+type renderCache struct {
+	lock    sync.Mutex
+	limit   int
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type renderEntry struct {
+	key      string
+	path     string
+	rendered *Rendered
+}
+
+var (
+	docCache     *renderCache
+	docCacheOnce sync.Once
+)
+
+// getRenderCache returns cache of rendered documents sized by "cache::render_size" in megabytes.
+func getRenderCache() *renderCache {
+	docCacheOnce.Do(func() {
+		docCache = newRenderCache(beego.AppConfig.DefaultInt("cache::render_size", 64) << 20)
+	})
+	return docCache
+}
+
+func newRenderCache(limit int) *renderCache {
+	return &renderCache{
+		limit:   limit,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// renderKey returns cache key of file by given path, which changes with the file.
+func renderKey(path string, fi os.FileInfo) string {
+	return path + "|" + strconv.FormatInt(fi.ModTime().UnixNano(), 10) + "|" + strconv.FormatInt(fi.Size(), 10)
+}
+
+func (c *renderCache) get(key string) (*Rendered, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		renderCacheStats.Add("misses", 1)
+		return nil, false
+	}
+	renderCacheStats.Add("hits", 1)
+	c.lru.MoveToFront(e)
+	return e.Value.(*renderEntry).rendered, true
+}
+
+func (c *renderCache) put(key, path string, r *Rendered) {
+	size := len(r.HTML)
+	if size > c.limit {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&renderEntry{key: key, path: path, rendered: r})
+	c.size += size
+
+	for c.size > c.limit {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *renderCache) remove(e *list.Element) {
+	entry := c.lru.Remove(e).(*renderEntry)
+	delete(c.entries, entry.key)
+	c.size -= len(entry.rendered.HTML)
+}
+
+// retain drops documents whose paths are not under dir,
+// e.g. ones of snapshots no longer served.
+func (c *renderCache) retain(dir string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if !strings.HasPrefix(e.Value.(*renderEntry).path, dir) {
+			c.remove(e)
+		}
+		e = next
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/astaxie/beego"
+)
+
+// FallbackLangs returns languages that documents missing in given language
+// are taken from, in order, by section "fallback" of app.conf.
+// Languages not listed there fall back to DefaultLang.
+func FallbackLangs(lang string) []string {
+	var chain []string
+	if v := beego.AppConfig.String("fallback::" + lang); len(v) > 0 {
+		chain = strings.Split(v, "|")
+	} else {
+		chain = []string{DefaultLang}
+	}
+
+	langs := make([]string, 0, len(chain))
+	for _, l := range chain {
+		l = strings.TrimSpace(l)
+		if len(l) == 0 || l == lang || containsString(langs, l) {
+			continue
+		}
+		langs = append(langs, l)
+	}
+	return langs
+}
+
+// mergeFallbacks adds documents missing in every language of roots from
+// its fallback languages, so that untranslated documents are listed and
+// served in the language they exist in.
+func mergeFallbacks(roots map[string]*DocRoot, dir string) {
+	for _, lang := range strings.Split(beego.AppConfig.String("lang::types"), "|") {
+		root := roots[lang]
+		for _, l := range FallbackLangs(lang) {
+			src := roots[l]
+			if src == nil {
+				continue
+			}
+			if root == nil {
+				root = newDocRoot(path.Join(dir, lang))
+				roots[lang] = root
+			}
+			root.merge(src)
+		}
+		if root != nil {
+			root.sortAll(root.Doc)
+		}
+	}
+}
+
+func newDocRoot(dir string) *DocRoot {
+	root := &DocRoot{
+		Path:    dir,
+		Lang:    path.Base(dir),
+		links:   make(map[string]*DocNode),
+		aliases: make(map[string]*DocNode),
+		pages:   make(map[string]*DocNode),
+	}
+	root.Doc = &DocNode{
+		IsDir: true,
+		Path:  dir,
+		dirs:  make(map[string]*DocNode),
+		Root:  root,
+	}
+	return root
+}
+
+// merge adds documents of src whose links d does not have.
+// Documents src itself takes from other languages are skipped,
+// they are merged from their own language when it is in the chain.
+func (d *DocRoot) merge(src *DocRoot) {
+	d.mergeDir(d.Doc, src.Doc, src.Lang)
+
+	for name, node := range src.pages {
+		if _, ok := d.pages[name]; ok || len(node.Fallback) > 0 {
+			continue
+		}
+		if !node.standalone {
+			// Documents of the tree are merged above.
+			if n, ok := d.links[node.Link]; ok && n.Fallback == src.Lang {
+				d.pages[name] = n
+			}
+			continue
+		}
+		d.pages[name] = borrowNode(node, nil, src.Lang)
+	}
+
+	for alias, node := range src.aliases {
+		if _, ok := d.aliases[alias]; ok {
+			continue
+		}
+		if _, ok := d.links[alias]; ok {
+			continue
+		}
+		if n, ok := d.links[node.Link]; ok {
+			d.aliases[alias] = n
+		}
+	}
+}
+
+func (d *DocRoot) mergeDir(dst, src *DocNode, lang string) {
+	for _, node := range src.Docs {
+		if len(node.Fallback) > 0 {
+			continue
+		}
+
+		if !node.IsDir {
+			if _, ok := d.links[node.Link]; ok {
+				continue
+			}
+			n := borrowNode(node, dst, lang)
+			dst.Docs = append(dst.Docs, n)
+			d.links[n.Link] = n
+			continue
+		}
+
+		name := filepath.Base(node.RelPath)
+		dir, ok := dst.dirs[name]
+		if !ok {
+			dir = borrowNode(node, dst, lang)
+			dir.Docs = nil
+			dir.dirs = make(map[string]*DocNode)
+			dst.Docs = append(dst.Docs, dir)
+			dst.dirs[name] = dir
+			if len(dir.Link) > 0 {
+				if _, ok := d.links[dir.Link]; !ok {
+					d.links[dir.Link] = dir
+				}
+			}
+		}
+		d.mergeDir(dir, node, lang)
+	}
+}
+
+// borrowNode returns copy of node of another language placed under parent.
+// It keeps root of its language so that it is rendered as such.
+func borrowNode(node, parent *DocNode, lang string) *DocNode {
+	n := *node
+	n.Parent = parent
+	n.Fallback = lang
+	return &n
+}
@@ -0,0 +1,342 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"encoding/xml"
+	"html"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+// BaseURL returns absolute URL of the site by "app::base_url", without trailing slash.
+func BaseURL() string {
+	return strings.TrimRight(beego.AppConfig.DefaultString("app::base_url", "http://beego.me"), "/")
+}
+
+func feedSize() int {
+	return beego.AppConfig.DefaultInt("feed::size", 20)
+}
+
+type atomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang    string       `xml:"xml:lang,attr"`
+	Title   string       `xml:"title"`
+	ID      string       `xml:"id"`
+	Links   []atomLink   `xml:"link"`
+	Updated string       `xml:"updated"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	Language      string     `xml:"language"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// feedItem is an entry of a feed in either format.
+type feedItem struct {
+	title     string
+	link      string
+	published time.Time
+	updated   time.Time
+	authors   []string
+	tags      []string
+	summary   string
+	content   string
+}
+
+// feedPosts returns newest translated blog posts of given language as feed items.
+func feedPosts(s *ContentStore, lang string) []*feedItem {
+	updated := treeUpdated(s.blogTree)
+
+	var items []*feedItem
+	for _, p := range s.posts[lang] {
+		if len(p.Fallback) > 0 {
+			continue
+		}
+		t := lastUpdated(p.DocNode, lang, updated)
+		if t.IsZero() {
+			// Posts of older syncs have neither date nor time of change.
+			if fi, err := os.Stat(p.FilePath); err == nil {
+				t = fi.ModTime()
+			}
+		}
+		link := BaseURL() + "/" + lang + "/blog/" + p.Slug
+		items = append(items, &feedItem{
+			title:     p.Name,
+			link:      link,
+			published: p.Date,
+			updated:   t,
+			authors:   p.Authors,
+			tags:      p.Tags,
+			summary:   p.Excerpt(),
+			content:   absoluteLinks(p.GetContent(), link),
+		})
+		if len(items) == feedSize() {
+			break
+		}
+	}
+	return items
+}
+
+// feedDocChanges returns documents of given language that changed upstream
+// most recently, as feed items.
+func feedDocChanges(s *ContentStore, lang string) []*feedItem {
+	root := s.docs[lang]
+	if root == nil {
+		return nil
+	}
+	updated := treeUpdated(s.docTree)
+
+	var items []*feedItem
+	for link, node := range root.links {
+		if !node.HasContent() || len(node.Fallback) > 0 {
+			continue
+		}
+		t := lastUpdated(node, lang, updated)
+		if t.IsZero() {
+			continue
+		}
+		items = append(items, &feedItem{
+			title:   node.Name,
+			link:    BaseURL() + "/" + lang + "/docs/" + link,
+			updated: t,
+			authors: node.Authors,
+			tags:    node.Tags,
+			summary: node.Summary,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].updated.Equal(items[j].updated) {
+			return items[i].updated.After(items[j].updated)
+		}
+		return items[i].link < items[j].link
+	})
+	if len(items) > feedSize() {
+		items = items[:feedSize()]
+	}
+	return items
+}
+
+var reLinkAttr = regexp.MustCompile(`\b(href|src)="([^"]*)"`)
+
+// absoluteLinks resolves links and images of rendered content against URL
+// of its page, feed readers show content away from the page.
+func absoluteLinks(content, page string) string {
+	base, err := url.Parse(page)
+	if err != nil {
+		return content
+	}
+	return reLinkAttr.ReplaceAllStringFunc(content, func(attr string) string {
+		m := reLinkAttr.FindStringSubmatch(attr)
+		ref, err := url.Parse(html.UnescapeString(m[2]))
+		if err != nil || ref.IsAbs() {
+			return attr
+		}
+		return m[1] + `="` + html.EscapeString(base.ResolveReference(ref).String()) + `"`
+	})
+}
+
+func treeUpdated(tree []oldDocNode) map[string]time.Time {
+	updated := make(map[string]time.Time, len(tree))
+	for _, node := range tree {
+		updated[node.Path] = node.Updated
+	}
+	return updated
+}
+
+// BlogAtom returns Atom feed of blog posts of given language version.
+func BlogAtom(lang, title string) ([]byte, error) {
+	self := BaseURL() + "/" + lang + "/blog/feed.atom"
+	return atom(lang, title, self, BaseURL()+"/"+lang+"/blog", feedPosts(CurrentStore(), lang))
+}
+
+// BlogRSS returns RSS 2.0 feed of blog posts of given language version.
+func BlogRSS(lang, title, description string) ([]byte, error) {
+	return rss(lang, title, description, BaseURL()+"/"+lang+"/blog", feedPosts(CurrentStore(), lang))
+}
+
+// DocChangesAtom returns Atom feed of documents of given language version
+// that changed upstream, by times syncs have seen them change.
+func DocChangesAtom(lang, title string) ([]byte, error) {
+	self := BaseURL() + "/" + lang + "/docs/changes.atom"
+	return atom(lang, title, self, BaseURL()+"/"+lang+"/docs/", feedDocChanges(CurrentStore(), lang))
+}
+
+func atom(lang, title, self, alternate string, items []*feedItem) ([]byte, error) {
+	feed := &atomFeed{
+		Lang:  lang,
+		Title: title,
+		ID:    self,
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: alternate, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	var latest time.Time
+	for _, item := range items {
+		entry := &atomEntry{
+			Title:   item.title,
+			ID:      item.link,
+			Links:   []atomLink{{Href: item.link, Rel: "alternate", Type: "text/html"}},
+			Updated: updatedTime(item).Format(time.RFC3339),
+		}
+		if !item.published.IsZero() {
+			entry.Published = item.published.Format(time.RFC3339)
+		}
+		for _, name := range item.authors {
+			entry.Authors = append(entry.Authors, atomPerson{Name: name})
+		}
+		for _, tag := range item.tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		if len(item.summary) > 0 {
+			entry.Summary = &atomText{Type: "text", Body: item.summary}
+		}
+		if len(item.content) > 0 {
+			entry.Content = &atomText{Type: "html", Body: item.content}
+		}
+		feed.Entries = append(feed.Entries, entry)
+
+		if t := updatedTime(item); t.After(latest) {
+			latest = t
+		}
+	}
+	if latest.IsZero() {
+		latest = time.Now()
+	}
+	feed.Updated = latest.Format(time.RFC3339)
+
+	return marshalFeed(feed)
+}
+
+func rss(lang, title, description, link string, items []*feedItem) ([]byte, error) {
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        link,
+			Description: description,
+			Language:    lang,
+		},
+	}
+
+	var latest time.Time
+	for _, item := range items {
+		ri := &rssItem{
+			Title:       item.title,
+			Link:        item.link,
+			GUID:        rssGUID{IsPermaLink: true, Value: item.link},
+			Description: item.summary,
+			Categories:  item.tags,
+		}
+		t := item.published
+		if t.IsZero() {
+			t = updatedTime(item)
+		}
+		if !t.IsZero() {
+			ri.PubDate = t.Format(time.RFC1123Z)
+			if t.After(latest) {
+				latest = t
+			}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, ri)
+	}
+	if latest.IsZero() {
+		latest = time.Now()
+	}
+	feed.Channel.LastBuildDate = latest.Format(time.RFC1123Z)
+
+	return marshalFeed(feed)
+}
+
+// updatedTime returns when item was updated, or published when that is unknown.
+func updatedTime(item *feedItem) time.Time {
+	if item.updated.IsZero() {
+		return item.published
+	}
+	return item.updated
+}
+
+func marshalFeed(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+// feedStore returns a store of a single blog post of en-US.
+func feedStore(t *testing.T) *ContentStore {
+	post := "---\n" +
+		"name: Routers & <Filters>\n" +
+		"date: \"2014-03-05T10:30:00+08:00\"\n" +
+		"author: astaxie\n" +
+		"tags: [router]\n" +
+		"---\n\n" +
+		"Read [the guide](../docs/mvc/controller/router.md#namespace) first.\n\n" +
+		"![Flow](images/flow.png)\n\n" +
+		"See [the API](/docs/api/) or [Go](https://golang.org/?a=1&b=2).\n"
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "blog", "en-US", "routers.md")
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(post), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	beego.AppConfig.Set("lang::types", "en-US")
+	s := new(ContentStore)
+	var err error
+	if s.blogs, err = parseDocs(filepath.Join(dir, "blog")); err != nil {
+		t.Fatal(err)
+	}
+	s.posts = buildPosts(s.blogs)
+	return s
+}
+
+func TestAtomFeed(t *testing.T) {
+	beego.AppConfig.Set("app::base_url", "https://beego.me/")
+	defer beego.AppConfig.Set("app::base_url", "")
+
+	items := feedPosts(feedStore(t), "en-US")
+	data, err := atom("en-US", "Blog & News", "https://beego.me/en-US/blog/feed.atom", "https://beego.me/en-US/blog", items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<title>Routers &amp; &lt;Filters&gt;</title>") {
+		t.Errorf("title is not escaped:\n%s", data)
+	}
+
+	feed := new(atomFeed)
+	if err = xml.Unmarshal(data, feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Blog & News" || len(feed.Entries) != 1 {
+		t.Fatalf("feed is %q with %d entries", feed.Title, len(feed.Entries))
+	}
+
+	entry := feed.Entries[0]
+	if entry.ID != "https://beego.me/en-US/blog/routers" {
+		t.Errorf("entry id is %q", entry.ID)
+	}
+	if entry.Published != "2014-03-05T10:30:00+08:00" {
+		t.Errorf("entry is published %q", entry.Published)
+	}
+	if _, err = time.Parse(time.RFC3339, entry.Updated); err != nil {
+		t.Errorf("entry is updated %q: %v", entry.Updated, err)
+	}
+	if feed.Updated != entry.Updated {
+		t.Errorf("feed is updated %q, entry %q", feed.Updated, entry.Updated)
+	}
+	if len(entry.Authors) != 1 || entry.Authors[0].Name != "astaxie" {
+		t.Errorf("entry authors are %v", entry.Authors)
+	}
+
+	if entry.Content == nil || entry.Content.Type != "html" {
+		t.Fatalf("entry content is %v", entry.Content)
+	}
+	for _, link := range []string{
+		`href="https://beego.me/en-US/docs/mvc/controller/router.md#namespace"`,
+		`src="https://beego.me/en-US/blog/images/flow.png"`,
+		`href="https://beego.me/docs/api/"`,
+		`href="https://golang.org/?a=1&amp;b=2"`,
+	} {
+		if !strings.Contains(entry.Content.Body, link) {
+			t.Errorf("content has no %s:\n%s", link, entry.Content.Body)
+		}
+	}
+}
+
+func TestRSSFeed(t *testing.T) {
+	beego.AppConfig.Set("app::base_url", "https://beego.me")
+	defer beego.AppConfig.Set("app::base_url", "")
+
+	items := feedPosts(feedStore(t), "en-US")
+	data, err := rss("en-US", "Blog", "News of <beego>", "https://beego.me/en-US/blog", items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<description>News of &lt;beego&gt;</description>") {
+		t.Errorf("description is not escaped:\n%s", data)
+	}
+
+	feed := new(rssFeed)
+	if err = xml.Unmarshal(data, feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("feed has %d items", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if item.Title != "Routers & <Filters>" || item.Link != "https://beego.me/en-US/blog/routers" || item.GUID.Value != item.Link {
+		t.Errorf("item is %q at %q, guid %q", item.Title, item.Link, item.GUID.Value)
+	}
+	if item.PubDate != "Wed, 05 Mar 2014 10:30:00 +0800" || feed.Channel.LastBuildDate != item.PubDate {
+		t.Errorf("item is published %q, feed built %q", item.PubDate, feed.Channel.LastBuildDate)
+	}
+}
+
+func TestAbsoluteLinks(t *testing.T) {
+	const page = "https://beego.me/en-US/blog/routers"
+	tests := []struct {
+		content, want string
+	}{
+		{`<a href="intro">x</a>`, `<a href="https://beego.me/en-US/blog/intro">x</a>`},
+		{`<a href="#top">x</a>`, `<a href="https://beego.me/en-US/blog/routers#top">x</a>`},
+		{`<img src="/static/img/a.png" alt="a">`, `<img src="https://beego.me/static/img/a.png" alt="a">`},
+		{`<a href="mailto:a@b.c">x</a>`, `<a href="mailto:a@b.c">x</a>`},
+		{`<a href="https://github.com/a?b=1&amp;c=2">x</a>`, `<a href="https://github.com/a?b=1&amp;c=2">x</a>`},
+		{`<p>href=&quot;x&quot; in text</p>`, `<p>href=&quot;x&quot; in text</p>`},
+	}
+	for _, test := range tests {
+		if got := absoluteLinks(test.content, page); got != test.want {
+			t.Errorf("absoluteLinks(%q) = %q, want %q", test.content, got, test.want)
+		}
+	}
+}
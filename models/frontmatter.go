@@ -0,0 +1,241 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+	"gopkg.in/yaml.v2"
+)
+
+// frontMatter is the metadata block between "---" lines at the top of a document.
+type frontMatter struct {
+	Root        bool     `yaml:"root"`
+	Name        string   `yaml:"name"`
+	Date        string   `yaml:"date"`
+	Link        string   `yaml:"link"`
+	Sort        int      `yaml:"sort"`
+	Description string   `yaml:"description"`
+	Summary     string   `yaml:"summary"`
+	Tags        []string `yaml:"tags"`
+	Author      string   `yaml:"author"`
+	Authors     []string `yaml:"authors"`
+	Aliases     []string `yaml:"aliases"`
+	Draft       bool     `yaml:"draft"`
+	Weight      int      `yaml:"weight"`
+	Since       string   `yaml:"since"`
+
+	// Extra keeps fields not listed above.
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// frontMatterLines is how many lines may precede the opening "---".
+const frontMatterLines = 3
+
+// States of front matter of a document.
+const (
+	noFrontMatter = iota
+	hasFrontMatter
+	// unclosedFrontMatter is an opening "---" without a closing one.
+	unclosedFrontMatter
+)
+
+// splitFrontMatter returns the front matter block of data without
+// its "---" lines, and the document body following it.
+// Documents without complete front matter are returned as body.
+func splitFrontMatter(data []byte) (meta, body []byte, state int) {
+	start := -1
+	offset := 0
+	for no := 0; offset < len(data); no++ {
+		end := bytes.IndexByte(data[offset:], '\n')
+		var line []byte
+		if end == -1 {
+			line = data[offset:]
+			end = len(data)
+		} else {
+			line = data[offset : offset+end]
+			end += offset + 1
+		}
+
+		if string(bytes.TrimSpace(line)) == "---" {
+			if start != -1 {
+				return data[start:offset], data[end:], hasFrontMatter
+			}
+			start = end
+		} else if start == -1 && no >= frontMatterLines {
+			break
+		}
+		offset = end
+	}
+	if start != -1 {
+		return nil, data, unclosedFrontMatter
+	}
+	return nil, data, noFrontMatter
+}
+
+// parseFrontMatter parses YAML front matter of document at path. Fields of
+// unexpected types are converted where possible, e.g. "tags: go" is a list
+// of one tag, or dropped with a warning. Blocks that are not valid YAML,
+// e.g. unquoted values containing ": ", are parsed line by line as older
+// documents were.
+func parseFrontMatter(path string, meta []byte) *frontMatter {
+	var fm *frontMatter
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(meta, &values); err != nil {
+		beego.Warn("models.parseFrontMatter -> document", path, "has invalid YAML front matter, read it line by line:", err)
+		fm = parseLegacyFrontMatter(meta)
+	} else {
+		fm = new(frontMatter)
+		for _, err := range fm.set(values) {
+			beego.Warn("models.parseFrontMatter -> document", path+":", err)
+		}
+	}
+
+	// A single "author" is listed first among "authors".
+	if len(fm.Author) > 0 && !containsFold(fm.Authors, fm.Author) {
+		fm.Authors = append([]string{fm.Author}, fm.Authors...)
+	}
+
+	// "weight" is what other generators call "sort".
+	if fm.Sort == 0 {
+		fm.Sort = fm.Weight
+	}
+	return fm
+}
+
+// set sets fields of fm by values of their YAML keys, other keys are kept in Extra.
+// It returns errors of values that can not be converted to their fields.
+func (fm *frontMatter) set(values map[string]interface{}) []error {
+	fields := make(map[string]reflect.Value)
+	v := reflect.ValueOf(fm).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if name := v.Type().Field(i).Tag.Get("yaml"); !strings.HasPrefix(name, ",") {
+			fields[name] = v.Field(i)
+		}
+	}
+
+	var errs []error
+	for key, value := range values {
+		field, ok := fields[key]
+		if !ok {
+			if fm.Extra == nil {
+				fm.Extra = make(map[string]interface{})
+			}
+			fm.Extra[key] = value
+			continue
+		}
+		if value == nil {
+			continue
+		}
+
+		var err error
+		switch field.Kind() {
+		case reflect.String:
+			switch value.(type) {
+			case []interface{}, map[interface{}]interface{}:
+				err = errors.New("not a string")
+			default:
+				field.SetString(metaString(value))
+			}
+		case reflect.Bool:
+			var b bool
+			if b, err = strconv.ParseBool(metaString(value)); err == nil {
+				field.SetBool(b)
+			}
+		case reflect.Int:
+			var n int
+			if n, err = strconv.Atoi(metaString(value)); err == nil {
+				field.SetInt(int64(n))
+			}
+		case reflect.Slice:
+			field.Set(reflect.ValueOf(metaStrings(value)))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v is not a %s", key, value, field.Kind()))
+		}
+	}
+	return errs
+}
+
+// metaString returns value of front matter as a string.
+func metaString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// metaStrings returns value of front matter as a list of strings,
+// a single string may list them separated by commas.
+func metaStrings(value interface{}) []string {
+	var list []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if item != nil {
+				list = append(list, metaString(item))
+			}
+		}
+	default:
+		// Lists of older documents may be written without YAML syntax, e.g. "[a, b".
+		for _, item := range strings.Split(strings.Trim(metaString(v), "[]"), ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// parseLegacyFrontMatter parses "key: value" lines.
+func parseLegacyFrontMatter(meta []byte) *frontMatter {
+	values := make(map[string]interface{})
+	sc := bufio.NewScanner(bytes.NewReader(meta))
+	for sc.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(sc.Text()), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	fm := new(frontMatter)
+	fm.set(values)
+	return fm
+}
+
+// parseDate parses date of front matter, in form of "Y-m-d H:i" used by
+// older documents, or RFC 3339 and plain dates.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return beego.DateParse(value, "Y-m-d H:i")
+}
@@ -0,0 +1,78 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		data       string
+		meta, body string
+		state      int
+	}{
+		{"---\nname: A\n---\nbody", "name: A\n", "body", hasFrontMatter},
+		{"\n\n---\nname: A\n---\n", "name: A\n", "", hasFrontMatter},
+		{"---\nname: A\nbody", "", "---\nname: A\nbody", unclosedFrontMatter},
+		{"# Title\n\nbody", "", "# Title\n\nbody", noFrontMatter},
+		{"a\nb\nc\nd\n---\nname: A\n---\n", "", "a\nb\nc\nd\n---\nname: A\n---\n", noFrontMatter},
+	}
+	for _, test := range tests {
+		meta, body, state := splitFrontMatter([]byte(test.data))
+		if string(meta) != test.meta || string(body) != test.body || state != test.state {
+			t.Errorf("splitFrontMatter(%q) = %q, %q, %d, want %q, %q, %d",
+				test.data, meta, body, state, test.meta, test.body, test.state)
+		}
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		meta string
+		want frontMatter
+	}{
+		// Headers of older beedoc documents.
+		{"name: Quick Start\nsort: 1", frontMatter{Name: "Quick Start", Sort: 1}},
+		{"root: true\nname: Introduction\nsort: 0", frontMatter{Root: true, Name: "Introduction"}},
+		{"name: Routing: the basics\nsort: 2", frontMatter{Name: "Routing: the basics", Sort: 2}},
+		{"name: Release: beego 1.4\ndate: 2014-05-20 10:00\nauthor: astaxie", frontMatter{
+			Name: "Release: beego 1.4", Date: "2014-05-20 10:00", Author: "astaxie", Authors: []string{"astaxie"}}},
+		{"name: A: b\ndraft: true\nimage: a.png", frontMatter{
+			Name: "A: b", Draft: true, Extra: map[string]interface{}{"image": "a.png"}}},
+
+		// YAML front matter.
+		{"name: Post\ndate: \"2020-01-02\"\ntags: [go, web]\nauthors: [a, b]\nsummary: Short", frontMatter{
+			Name: "Post", Date: "2020-01-02", Tags: []string{"go", "web"}, Authors: []string{"a", "b"}, Summary: "Short"}},
+		{"name: Post\nauthor: c\nauthors:\n  - a\n  - c", frontMatter{Name: "Post", Author: "c", Authors: []string{"a", "c"}}},
+		{"name: Post\nweight: 3\naliases:\n  - old.md", frontMatter{Name: "Post", Sort: 3, Weight: 3, Aliases: []string{"old.md"}}},
+		{"name: 123\nsince: 1.4", frontMatter{Name: "123", Since: "1.4"}},
+		{"image: a.png\nseries: {name: s}", frontMatter{Extra: map[string]interface{}{
+			"image": "a.png", "series": map[interface{}]interface{}{"name": "s"}}}},
+
+		// A field of unexpected type does not discard the others.
+		{"name: Post\ntags: go\naliases: old.md", frontMatter{Name: "Post", Tags: []string{"go"}, Aliases: []string{"old.md"}}},
+		{"name: Post\nsort: first\ntags: [go]", frontMatter{Name: "Post", Tags: []string{"go"}}},
+		{"name: [a, b]\ndraft: maybe\ntags: [go]", frontMatter{Tags: []string{"go"}}},
+		{"name: Post\ntags: [go, web", frontMatter{Name: "Post", Tags: []string{"go", "web"}}},
+	}
+	for _, test := range tests {
+		got := parseFrontMatter("test.md", []byte(test.meta))
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("parseFrontMatter(%q) = %+v, want %+v", test.meta, *got, test.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"context"
+	"sync"
+
+	"github.com/astaxie/beego"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+// changeSet is the list of upstream files changed by a sync.
+type changeSet struct {
+	Changed map[string]bool
+	Deleted []string
+}
+
+// syncer is implemented by content sources that keep a local copy of upstream,
+// and therefore know exactly which files changed since last sync.
+type syncer interface {
+	Sync(ctx context.Context) (*changeSet, error)
+}
+
+// gitSource reads files from a local git repository.
+// When url is set, the repository is a bare clone of url that is
+// fetched and fast-forwarded by Sync, otherwise its HEAD is used as it is.
+type gitSource struct {
+	url    string
+	dir    string
+	branch string
+	depth  int
+
+	// repo is opened by Sync and read by Tree and Fetch until next one.
+	mu   sync.Mutex
+	repo *git.Repository
+}
+
+// open returns the repository opened by last Sync, or opens it.
+func (s *gitSource) open() (*git.Repository, error) {
+	if s.repo == nil {
+		repo, err := git.PlainOpen(s.dir)
+		if err != nil {
+			return nil, err
+		}
+		s.repo = repo
+	}
+	return s.repo, nil
+}
+
+// headTree returns tree of HEAD commit, callers hold s.mu.
+func (s *gitSource) headTree() (*object.Tree, error) {
+	repo, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
+
+func (s *gitSource) Tree(ctx context.Context) ([]*TreeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tree, err := s.headTree()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*TreeEntry
+	err = tree.Files().ForEach(func(f *object.File) error {
+		entries = append(entries, &TreeEntry{
+			Path: f.Name,
+			Sha:  f.Hash.String(),
+			Type: "blob",
+		})
+		return nil
+	})
+	return entries, err
+}
+
+func (s *gitSource) Fetch(ctx context.Context, path string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tree, err := s.headTree()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := tree.File(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := f.Contents()
+	return []byte(content), err
+}
+
+// Sync clones the repository when it does not exist yet, otherwise fetches
+// the branch and fast-forwards local one to it. Changed files are derived
+// from the diff between the old and the new commit.
+func (s *gitSource) Sync(ctx context.Context) (*changeSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := &changeSet{Changed: make(map[string]bool)}
+	s.repo = nil
+	if len(s.url) == 0 {
+		// Managed by someone else, SHA comparison will find out changes.
+		return changes, nil
+	}
+
+	localRef := plumbing.NewBranchReferenceName(s.branch)
+	remoteRef := plumbing.NewRemoteReferenceName("origin", s.branch)
+
+	repo, err := s.open()
+	if err == git.ErrRepositoryNotExists {
+		beego.Info("Cloning", s.url, "into", s.dir)
+		repo, err = git.PlainCloneContext(ctx, s.dir, true, &git.CloneOptions{
+			URL:           s.url,
+			ReferenceName: localRef,
+			SingleBranch:  true,
+			Depth:         s.depth,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.repo = repo
+
+		// Everything is new.
+		tree, err := s.headTree()
+		if err != nil {
+			return nil, err
+		}
+		err = tree.Files().ForEach(func(f *object.File) error {
+			changes.Changed[f.Name] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return changes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec("+" + localRef + ":" + remoteRef)},
+		Depth:    s.depth,
+		Force:    true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, err
+	}
+
+	oldRef, err := repo.Reference(localRef, true)
+	if err != nil {
+		return nil, err
+	}
+	newRef, err := repo.Reference(remoteRef, true)
+	if err != nil {
+		return nil, err
+	}
+	if oldRef.Hash() == newRef.Hash() {
+		return changes, nil
+	}
+
+	oldCommit, err := repo.CommitObject(oldRef.Hash())
+	if err != nil {
+		return nil, err
+	}
+	newCommit, err := repo.CommitObject(newRef.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	// History of a shallow clone may not reach the old commit, diff of trees works anyway.
+	if ok, err := oldCommit.IsAncestor(newCommit); err != nil || !ok {
+		beego.Warn("models.gitSource.Sync -> "+s.url+" is not a fast-forward, resetting to", newCommit.Hash)
+	}
+
+	oldTree, err := oldCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := newCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := object.DiffTree(oldTree, newTree)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range diff {
+		action, err := c.Action()
+		if err != nil {
+			return nil, err
+		}
+
+		switch action {
+		case merkletrie.Insert, merkletrie.Modify:
+			changes.Changed[c.To.Name] = true
+		case merkletrie.Delete:
+			changes.Deleted = append(changes.Deleted, c.From.Name)
+		}
+	}
+
+	// Fast-forward, HEAD of the bare repository points to local branch.
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(localRef, newRef.Hash())); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// upstream is a non-bare repository that plays the remote of a gitSource.
+type upstream struct {
+	t    *testing.T
+	dir  string
+	repo *git.Repository
+}
+
+func newUpstream(t *testing.T) *upstream {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &upstream{t: t, dir: dir, repo: repo}
+}
+
+func (u *upstream) write(name, content string) {
+	name = filepath.Join(u.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		u.t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		u.t.Fatal(err)
+	}
+}
+
+func (u *upstream) commit(msg string) plumbing.Hash {
+	wt, err := u.repo.Worktree()
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	if err = wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		u.t.Fatal(err)
+	}
+	hash, err := wt.Commit(msg, &git.CommitOptions{
+		Author: &object.Signature{Name: "beeweb", Email: "beeweb@example.com", When: time.Now()},
+	})
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	return hash
+}
+
+func (u *upstream) remove(name string) {
+	if err := os.Remove(filepath.Join(u.dir, filepath.FromSlash(name))); err != nil {
+		u.t.Fatal(err)
+	}
+}
+
+func TestGitSourceSync(t *testing.T) {
+	up := newUpstream(t)
+	up.write("en-US/intro.md", "intro")
+	up.write("en-US/install.md", "install")
+	up.write("en-US/old.md", "old")
+	up.write("en-US/moved.md", "moved")
+	up.commit("initial")
+
+	src := &gitSource{
+		url:    "file://" + filepath.ToSlash(up.dir),
+		dir:    filepath.Join(t.TempDir(), "clone"),
+		branch: "master",
+	}
+	ctx := context.Background()
+
+	changes, err := src.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertChanges(t, "clone", changes,
+		[]string{"en-US/install.md", "en-US/intro.md", "en-US/moved.md", "en-US/old.md"}, nil)
+
+	// Nothing new upstream.
+	if changes, err = src.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertChanges(t, "no change", changes, nil, nil)
+
+	up.write("en-US/new.md", "new")
+	up.write("en-US/intro.md", "intro, revised")
+	up.remove("en-US/old.md")
+	up.remove("en-US/moved.md")
+	up.write("en-US/renamed.md", "moved")
+	head := up.commit("add, modify, delete and rename")
+
+	if changes, err = src.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertChanges(t, "fetch", changes,
+		[]string{"en-US/intro.md", "en-US/new.md", "en-US/renamed.md"},
+		[]string{"en-US/moved.md", "en-US/old.md"})
+
+	repo, err := git.PlainOpen(src.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Hash() != head {
+		t.Errorf("local branch is at %s, want %s", ref.Hash(), head)
+	}
+
+	data, err := src.Fetch(ctx, "en-US/intro.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "intro, revised" {
+		t.Errorf("Fetch returned %q, want content of the new commit", data)
+	}
+	if _, err = src.Fetch(ctx, "en-US/old.md"); err == nil {
+		t.Error("Fetch of deleted file succeeded")
+	}
+}
+
+func assertChanges(t *testing.T, name string, changes *changeSet, changed, deleted []string) {
+	var got []string
+	for p := range changes.Changed {
+		got = append(got, p)
+	}
+	sort.Strings(got)
+	sort.Strings(changes.Deleted)
+
+	if !reflect.DeepEqual(got, changed) {
+		t.Errorf("%s: changed %v, want %v", name, got, changed)
+	}
+	if !(len(changes.Deleted) == 0 && len(deleted) == 0) && !reflect.DeepEqual(changes.Deleted, deleted) {
+		t.Errorf("%s: deleted %v, want %v", name, changes.Deleted, deleted)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/astaxie/beego"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// languageAliases maps fence languages used by documents to chroma lexers.
+var languageAliases = map[string]string{
+	"shell":    "bash",
+	"sh":       "bash",
+	"console":  "bash",
+	"conf":     "ini",
+	"tpl":      "go-html-template",
+	"template": "go-html-template",
+	"gohtml":   "go-html-template",
+}
+
+// codeHighlighter renders fenced code blocks with classes of chroma tokens.
+// Lines are highlighted by ranges in braces after the language, e.g. "go {3-5,8}".
+type codeHighlighter struct {
+	lineNumbers bool
+}
+
+func (h *codeHighlighter) Extend(m goldmark.Markdown) {
+	// Runs before the default renderer of code blocks.
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(h, 200)))
+}
+
+func (h *codeHighlighter) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, h.renderFencedCodeBlock)
+}
+
+func (h *codeHighlighter) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+
+	var lang, attrs string
+	if n.Info != nil {
+		lang, attrs = parseFenceInfo(string(n.Info.Segment.Value(source)))
+	}
+
+	var code bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	lexer := lexers.Get(lang)
+	if alias, ok := languageAliases[lang]; ok {
+		lexer = lexers.Get(alias)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	it, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	formatter := chromahtml.New(
+		chromahtml.WithClasses(true),
+		chromahtml.WithLineNumbers(h.lineNumbers),
+		chromahtml.HighlightLines(parseLineRanges(attrs)))
+	if err = formatter.Format(w, highlightStyle(), it); err != nil {
+		return ast.WalkStop, err
+	}
+	w.WriteByte('\n')
+	return ast.WalkSkipChildren, nil
+}
+
+// parseFenceInfo splits info string of a fence into language and attributes in braces.
+func parseFenceInfo(info string) (lang, attrs string) {
+	info = strings.TrimSpace(info)
+	if i := strings.Index(info, "{"); i > -1 {
+		attrs = strings.TrimSuffix(strings.TrimSpace(info[i+1:]), "}")
+		info = info[:i]
+	}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		lang = strings.ToLower(fields[0])
+	}
+	return lang, attrs
+}
+
+// parseLineRanges parses line numbers like "3-5,8" into ranges of lines.
+func parseLineRanges(attrs string) [][2]int {
+	var ranges [][2]int
+	for _, v := range strings.FieldsFunc(attrs, func(r rune) bool { return r == ',' || r == ' ' }) {
+		parts := strings.SplitN(v, "-", 2)
+		start, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		end := start
+		if len(parts) == 2 {
+			if end, err = strconv.Atoi(parts[1]); err != nil || end < start {
+				continue
+			}
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges
+}
+
+func highlightStyle() *chroma.Style {
+	return styles.Get(beego.AppConfig.DefaultString("markdown::highlight_style", "github"))
+}
+
+var (
+	highlightCSS     []byte
+	highlightCSSErr  error
+	highlightCSSOnce sync.Once
+)
+
+// HighlightCSS returns stylesheet of highlighted code blocks by "markdown::highlight_style".
+func HighlightCSS() ([]byte, error) {
+	highlightCSSOnce.Do(func() {
+		var buf bytes.Buffer
+		highlightCSSErr = chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&buf, highlightStyle())
+		highlightCSS = buf.Bytes()
+	})
+	return highlightCSS, highlightCSSErr
+}
@@ -0,0 +1,117 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+var (
+	pendingLock     sync.Mutex
+	pendingSections = make(map[string]bool)
+	pendingTimer    *time.Timer
+	pendingSince    time.Time // When the first of pending pushes came.
+)
+
+// SectionsForPush returns names of sections mirrored from the repository and branch of a push.
+// Repository is matched by any of its URLs against URL of upstream repository of sections,
+// so that local sources, which have none, are never matched.
+func SectionsForPush(urls []string, ref string) []string {
+	var names []string
+	for _, sec := range sections {
+		if len(sec.URL) == 0 || ref != "refs/heads/"+sec.Branch {
+			continue
+		}
+
+		for _, u := range urls {
+			if normalizeRepoURL(sec.URL) == normalizeRepoURL(u) {
+				names = append(names, sec.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+func normalizeRepoURL(u string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git"))
+}
+
+// ScheduleUpdate requests file updates of given section. The sync starts after
+// a quiet period, so that a burst of pushes results in a single sync, but no
+// later than "hooks::max_wait" after the first push of the burst.
+func ScheduleUpdate(section string) {
+	delay := hookDuration("debounce", 10*time.Second)
+	maxWait := hookDuration("max_wait", 60*time.Second)
+
+	pendingLock.Lock()
+	defer pendingLock.Unlock()
+
+	pendingSections[section] = true
+	if pendingTimer == nil {
+		pendingSince = time.Now()
+		pendingTimer = time.AfterFunc(delay, runPendingUpdates)
+		return
+	}
+
+	if left := maxWait - time.Since(pendingSince); left < delay {
+		delay = left
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	pendingTimer.Reset(delay)
+}
+
+// hookDuration returns duration of given key of section "hooks" in app.conf,
+// in seconds or in the form of time.ParseDuration, e.g. "500ms".
+func hookDuration(key string, def time.Duration) time.Duration {
+	v := beego.AppConfig.String("hooks::" + key)
+	if n, err := strconv.Atoi(v); err == nil {
+		return time.Duration(n) * time.Second
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d
+	}
+	return def
+}
+
+// syncPending syncs sections of pending pushes, tests replace it.
+var syncPending = updateSections
+
+func runPendingUpdates() {
+	pendingLock.Lock()
+	names := make([]string, 0, len(pendingSections))
+	for name := range pendingSections {
+		names = append(names, name)
+	}
+	pendingSections = make(map[string]bool)
+	pendingTimer = nil
+	pendingLock.Unlock()
+
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	if err := syncPending(names...); err != nil {
+		beego.Error(err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+func TestSectionsForPush(t *testing.T) {
+	saved := sections
+	defer func() { sections = saved }()
+	sections = []*contentSection{
+		{Name: "docs", URL: "https://github.com/beego/beedoc", Branch: "master"},
+		{Name: "blog", URL: "https://git.example.com/beego/beeblog", Branch: "main"},
+		{Name: "products", URL: "", Branch: "master"},
+	}
+
+	tests := []struct {
+		urls []string
+		ref  string
+		want []string
+	}{
+		{[]string{"https://github.com/beego/beedoc.git", "git@github.com:beego/beedoc.git", "https://github.com/beego/beedoc"}, "refs/heads/master", []string{"docs"}},
+		{[]string{"https://GitHub.com/Beego/BeeDoc/"}, "refs/heads/master", []string{"docs"}},
+		{[]string{"https://github.com/beego/beedoc"}, "refs/heads/develop", nil},
+		{[]string{"https://github.com/beego/beedoc"}, "refs/tags/master", nil},
+		{[]string{"https://git.example.com/beego/beeblog.git"}, "refs/heads/main", []string{"blog"}},
+		{[]string{"https://github.com/beego/beeblog"}, "refs/heads/main", nil},
+		{[]string{"https://github.com/beego/products"}, "refs/heads/master", nil},
+		{[]string{"", ""}, "refs/heads/master", nil},
+	}
+	for _, test := range tests {
+		if got := SectionsForPush(test.urls, test.ref); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SectionsForPush(%q, %q) = %q, want %q", test.urls, test.ref, got, test.want)
+		}
+	}
+}
+
+// syncRecorder records syncs of pending pushes.
+type syncRecorder struct {
+	mu    sync.Mutex
+	start time.Time
+	syncs []string
+	times []time.Duration
+}
+
+func (r *syncRecorder) sync(names ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.syncs = append(r.syncs, strings.Join(names, ","))
+	r.times = append(r.times, time.Since(r.start))
+	return nil
+}
+
+func (r *syncRecorder) result() ([]string, []time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.syncs...), append([]time.Duration(nil), r.times...)
+}
+
+func TestScheduleUpdate(t *testing.T) {
+	defer func(f func(...string) error) { syncPending = f }(syncPending)
+	beego.AppConfig.Set("hooks::debounce", "150ms")
+	beego.AppConfig.Set("hooks::max_wait", "400ms")
+	defer beego.AppConfig.Set("hooks::debounce", "")
+	defer beego.AppConfig.Set("hooks::max_wait", "")
+
+	tests := []struct {
+		name     string
+		pushes   []string      // Sections pushed to, one push every interval.
+		interval time.Duration // Between pushes.
+		syncs    []string      // First syncs.
+		more     bool          // Whether more syncs may follow.
+		min, max time.Duration // Of time of the first sync after the first push.
+	}{
+		{"one push", []string{"docs"}, 0, []string{"docs"}, false, 150 * time.Millisecond, 350 * time.Millisecond},
+		{"burst", []string{"docs", "blog", "docs"}, 30 * time.Millisecond, []string{"blog,docs"}, false, 210 * time.Millisecond, 410 * time.Millisecond},
+		// Pushes keep coming within the debounce, the cap fires the sync.
+		{"stream", []string{"docs", "docs", "docs", "docs", "docs", "docs", "docs", "docs", "docs", "docs", "docs", "docs", "docs", "docs"},
+			50 * time.Millisecond, []string{"docs"}, true, 400 * time.Millisecond, 600 * time.Millisecond},
+	}
+	for _, test := range tests {
+		r := &syncRecorder{start: time.Now()}
+		syncPending = r.sync
+
+		for i, section := range test.pushes {
+			if i > 0 {
+				time.Sleep(test.interval)
+			}
+			ScheduleUpdate(section)
+		}
+		time.Sleep(400 * time.Millisecond)
+
+		syncs, times := r.result()
+		if len(syncs) < len(test.syncs) || !test.more && len(syncs) > len(test.syncs) ||
+			!reflect.DeepEqual(syncs[:len(test.syncs)], test.syncs) {
+			t.Errorf("%s: synced %q, want %q", test.name, syncs, test.syncs)
+			continue
+		}
+		if times[0] < test.min || times[0] > test.max {
+			t.Errorf("%s: first sync after %v, want within [%v, %v]", test.name, times[0], test.min, test.max)
+		}
+		if test.more && len(syncs) >= len(test.pushes) {
+			t.Errorf("%s: %d pushes synced %d times", test.name, len(test.pushes), len(syncs))
+		}
+	}
+}
+
+func TestHookDuration(t *testing.T) {
+	defer beego.AppConfig.Set("hooks::debounce", "")
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 10 * time.Second},
+		{"15", 15 * time.Second},
+		{"1m30s", 90 * time.Second},
+		{"250ms", 250 * time.Millisecond},
+		{"soon", 10 * time.Second},
+	}
+	for _, test := range tests {
+		beego.AppConfig.Set("hooks::debounce", test.value)
+		if got := hookDuration("debounce", 10*time.Second); got != test.want {
+			t.Errorf("hookDuration(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
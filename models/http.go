@@ -16,12 +16,22 @@
 package models
 
 import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"expvar"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/astaxie/beego"
@@ -57,59 +67,325 @@ var (
 	httpClient    = &http.Client{Transport: httpTransport}
 )
 
-func getHttpJson(url string, v interface{}) error {
+// httpStatusError is returned for responses other than 200 OK.
+type httpStatusError struct {
+	url    string
+	code   int
+	status string
+	header http.Header
+}
+
+func (e *httpStatusError) Error() string {
+	return "can't get infomation from " + e.url + ": " + e.status
+}
+
+// retryAfter returns how long to wait before sending request again after
+// failed response, false if the request should not be retried.
+// GitHub reports exhausted rate limit with 403 and X-RateLimit-* headers.
+func (e *httpStatusError) retryAfter() (time.Duration, bool) {
+	if sec, err := strconv.Atoi(e.header.Get("Retry-After")); err == nil {
+		return time.Duration(sec) * time.Second, true
+	}
+	if e.header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(e.header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0).Sub(time.Now()) + time.Second, true
+		}
+	}
+	return 0, e.code == http.StatusTooManyRequests || e.code >= 500
+}
+
+// httpGet sends GET request with given extra header, the caller must close body of the response.
+// Requests failed by server errors or rate limits are retried with exponential backoff.
+func httpGet(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	retries := beego.AppConfig.DefaultInt("fetch::retries", 3)
+	maxWait := time.Duration(beego.AppConfig.DefaultInt("fetch::max_wait", 300)) * time.Second
+
+	scope := takeCacheScope(ctx)
+	backoff := time.Second
+	for i := 0; ; i++ {
+		resp, err := httpGetOnce(ctx, url, header, scope)
+		if err == nil {
+			return resp, nil
+		}
+
+		statusErr, ok := err.(*httpStatusError)
+		if !ok || i >= retries {
+			return nil, err
+		}
+		wait, ok := statusErr.retryAfter()
+		if !ok {
+			return nil, err
+		}
+		if wait < backoff {
+			wait = backoff
+		}
+		if wait > maxWait {
+			return nil, errors.New(err.Error() + ", retry after " + wait.String())
+		}
+
+		beego.Warn("Retry", url, "in", wait, "for", statusErr.status)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func httpGetOnce(ctx context.Context, url string, header http.Header, scope *cacheScope) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
 	}
 	req.Header.Set("User-Agent", userAgent)
 
+	if scope != nil {
+		scope.prepare(req)
+	}
+
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode == 200 {
-		err = json.NewDecoder(resp.Body).Decode(v)
-		if _, ok := err.(*json.SyntaxError); ok {
-			return errors.New("JSON syntax error at " + url)
+	if scope != nil {
+		switch resp.StatusCode {
+		case http.StatusNotModified:
+			httpCacheStats.Add("hits", 1)
+			resp.Body.Close()
+			return nil, errNotModified
+		case http.StatusOK:
+			httpCacheStats.Add("misses", 1)
+			scope.keep(url, resp.Header)
 		}
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, &httpStatusError{url: url, code: resp.StatusCode, status: resp.Status, header: resp.Header}
+	}
+	return resp, nil
+}
+
+// getHttpJson decodes JSON response of given URL into v and returns response header.
+func getHttpJson(ctx context.Context, url string, header http.Header, v interface{}) (http.Header, error) {
+	resp, err := httpGet(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if _, ok := err.(*json.SyntaxError); ok {
+		return nil, errors.New("JSON syntax error at " + url)
+	}
+	return resp.Header, err
+}
+
+// getHttpRaw returns response body of given URL.
+func getHttpRaw(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	resp, err := httpGet(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// errNotModified is returned for a conditional request when the resource
+// has not changed since it was last fetched.
+var errNotModified = errors.New("not modified")
+
+const httpCacheDir = "content/httpcache"
+
+var httpCacheStats = expvar.NewMap("httpcache")
+
+// cacheEntry keeps validators of a response.
+type cacheEntry struct {
+	URL          string
+	ETag         string
+	LastModified string
+}
+
+func cacheEntryPath(url string) string {
+	h := sha1.Sum([]byte(url))
+	return path.Join(httpCacheDir, hex.EncodeToString(h[:])+".json")
+}
+
+func loadCacheEntry(url string) *cacheEntry {
+	data, err := ioutil.ReadFile(cacheEntryPath(url))
+	if err != nil {
+		return nil
+	}
+
+	e := new(cacheEntry)
+	if err = json.Unmarshal(data, e); err != nil || e.URL != url {
 		return nil
 	}
-	return errors.New("can't get infomation")
+	return e
 }
 
-func getFiles(files []*rawFile) error {
-	ch := make(chan error, len(files))
-	for i := range files {
-		go func(i int) {
-			req, err := http.NewRequest("GET", files[i].rawURL, nil)
-			if err != nil {
-				ch <- err
-				return
-			}
-			req.Header.Set("User-Agent", userAgent)
-			resp, err := httpClient.Do(req)
-			if err != nil {
-				ch <- err
-				return
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode == 200 {
-				p, err := ioutil.ReadAll(resp.Body)
-				if err != nil {
-					ch <- err
-					return
-				}
-				files[i].data = p
+// cacheScope makes the first request sent with its context conditional on
+// validators saved on disk. Validators of a new response are kept aside
+// until commit, so they are saved only after the response has been processed.
+type cacheScope struct {
+	lock        sync.Mutex
+	conditional bool
+	used        bool
+	url         string
+	entry       *cacheEntry
+
+	// partial is true when the response is the first page of a listing,
+	// which may stay the same while later pages change.
+	partial bool
+}
+
+type cacheScopeKey struct{}
+
+// withCacheScope returns a context whose first request is cached, conditional
+// is false when validators on disk should not be trusted this time.
+func withCacheScope(ctx context.Context, conditional bool) (context.Context, *cacheScope) {
+	scope := &cacheScope{conditional: conditional}
+	return context.WithValue(ctx, cacheScopeKey{}, scope), scope
+}
+
+// takeCacheScope returns the scope of ctx if this is its first request.
+func takeCacheScope(ctx context.Context) *cacheScope {
+	scope, ok := ctx.Value(cacheScopeKey{}).(*cacheScope)
+	if !ok {
+		return nil
+	}
+
+	scope.lock.Lock()
+	defer scope.lock.Unlock()
+	if scope.used {
+		return nil
+	}
+	scope.used = true
+	return scope
+}
+
+// uncacheable marks response of the first request of ctx as the first page of
+// a listing of several, its validators are never saved and it is always sent
+// unconditionally, since 304 on it tells nothing about the other pages.
+func uncacheable(ctx context.Context) {
+	if scope, ok := ctx.Value(cacheScopeKey{}).(*cacheScope); ok {
+		scope.lock.Lock()
+		scope.partial = true
+		scope.lock.Unlock()
+	}
+}
+
+func (scope *cacheScope) prepare(req *http.Request) {
+	if !scope.conditional {
+		return
+	}
+	if e := loadCacheEntry(req.URL.String()); e != nil {
+		if len(e.ETag) > 0 {
+			req.Header.Set("If-None-Match", e.ETag)
+		}
+		if len(e.LastModified) > 0 {
+			req.Header.Set("If-Modified-Since", e.LastModified)
+		}
+	}
+}
+
+func (scope *cacheScope) keep(url string, header http.Header) {
+	scope.url = url
+	e := &cacheEntry{
+		URL:          url,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+	if len(e.ETag) > 0 || len(e.LastModified) > 0 {
+		scope.entry = e
+	}
+}
+
+// commit saves validators of the response received in the scope.
+func (scope *cacheScope) commit() {
+	if scope.partial {
+		// Validators saved while the listing had a single page are stale.
+		if len(scope.url) > 0 {
+			os.Remove(cacheEntryPath(scope.url))
+		}
+		return
+	}
+	if scope.entry == nil {
+		return
+	}
+
+	data, err := json.Marshal(scope.entry)
+	if err == nil {
+		os.MkdirAll(httpCacheDir, os.ModePerm)
+		err = ioutil.WriteFile(cacheEntryPath(scope.entry.URL), data, 0644)
+	}
+	if err != nil {
+		beego.Error("models.cacheScope.commit ->", err)
+	}
+}
+
+// commitCacheScopes saves validators of given scopes.
+func commitCacheScopes(scopes []*cacheScope) {
+	for _, scope := range scopes {
+		scope.commit()
+	}
+}
+
+// fetchErrors reports files that could not be fetched.
+type fetchErrors []*rawFile
+
+func (e fetchErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, f := range e {
+		msgs = append(msgs, f.path+": "+f.err.Error())
+	}
+	return fmt.Sprintf("%d files failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// getFiles fetches files with a pool of "fetch::workers" workers.
+// A failed file does not stop others, its error is kept in the file
+// and all failures are returned as fetchErrors.
+func getFiles(ctx context.Context, src ContentSource, files []*rawFile) error {
+	workers := beego.AppConfig.DefaultInt("fetch::workers", 8)
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan *rawFile)
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < len(files); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				f.data, f.err = src.Fetch(ctx, f.path)
 			}
-			ch <- nil
-		}(i)
+		}()
+	}
+
+	for _, f := range files {
+		if ctx.Err() != nil {
+			f.err = ctx.Err()
+			continue
+		}
+		queue <- f
 	}
-	for _ = range files {
-		if err := <-ch; err != nil {
-			return err
+	close(queue)
+	wg.Wait()
+
+	var errs fetchErrors
+	for _, f := range files {
+		if f.err != nil {
+			beego.Error("models.getFiles -> fetch "+f.path+":", f.err)
+			errs = append(errs, f)
 		}
 	}
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
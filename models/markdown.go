@@ -0,0 +1,337 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/astaxie/beego"
+	"github.com/slene/blackfriday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/beego/beeweb/analysis"
+)
+
+// Heading is an entry of table of contents of a document.
+type Heading struct {
+	Level    int
+	Text     string
+	ID       string
+	Children []*Heading
+}
+
+// Rendered is a markdown document rendered to HTML with its table of contents.
+type Rendered struct {
+	HTML     []byte
+	Headings []*Heading
+}
+
+// Renderer converts markdown source of document in given language to HTML.
+type Renderer interface {
+	Render(source []byte, lang string) (*Rendered, error)
+}
+
+// Levels of headings listed in table of contents,
+// the level 1 heading is title of the document.
+const (
+	tocMinLevel = 2
+	tocMaxLevel = 4
+)
+
+var (
+	mdRenderer Renderer
+	mdOnce     sync.Once
+)
+
+// markdownExtensions are goldmark extensions that can be enabled by "markdown::extensions".
+var markdownExtensions = map[string]goldmark.Extender{
+	"table":          extension.Table,
+	"strikethrough":  extension.Strikethrough,
+	"linkify":        extension.Linkify,
+	"tasklist":       extension.TaskList,
+	"footnote":       extension.Footnote,
+	"definitionlist": extension.DefinitionList,
+	"typographer":    extension.Typographer,
+}
+
+const defaultMarkdownExtensions = "table|strikethrough|linkify|tasklist|footnote|definitionlist"
+
+// initMarkdown creates renderer by section "markdown" of app.conf.
+func initMarkdown() {
+	conf := func(key, def string) string {
+		return beego.AppConfig.DefaultString("markdown::"+key, def)
+	}
+
+	switch name := conf("renderer", "goldmark"); name {
+	case "blackfriday":
+		mdRenderer = blackfridayRenderer{}
+	default:
+		if name != "goldmark" {
+			beego.Error("models.initMarkdown -> unknown renderer " + name + ", use goldmark")
+		}
+
+		var exts []goldmark.Extender
+		for _, v := range strings.Split(conf("extensions", defaultMarkdownExtensions), "|") {
+			v = strings.TrimSpace(v)
+			if len(v) == 0 {
+				continue
+			}
+			ext, ok := markdownExtensions[v]
+			if !ok {
+				beego.Error("models.initMarkdown -> unknown extension " + v)
+				continue
+			}
+			exts = append(exts, ext)
+		}
+		if beego.AppConfig.DefaultBool("markdown::highlight", true) {
+			exts = append(exts, &codeHighlighter{
+				lineNumbers: beego.AppConfig.DefaultBool("markdown::line_numbers", true),
+			})
+		}
+
+		mdRenderer = newGoldmarkRenderer(exts,
+			beego.AppConfig.DefaultBool("markdown::heading_ids", true),
+			beego.AppConfig.DefaultBool("markdown::hard_wraps", true),
+			beego.AppConfig.DefaultBool("markdown::unsafe", true))
+	}
+}
+
+// goldmarkRenderer renders CommonMark with GitHub Flavored Markdown extensions.
+type goldmarkRenderer struct {
+	md goldmark.Markdown
+}
+
+func newGoldmarkRenderer(exts []goldmark.Extender, headingIDs, hardWraps, unsafe bool) *goldmarkRenderer {
+	// Headings may set their IDs as "## Title {#id}", which keeps anchors
+	// of translated documents the same.
+	parserOpts := []parser.Option{parser.WithAttribute()}
+	if headingIDs {
+		parserOpts = append(parserOpts, parser.WithAutoHeadingID())
+	}
+
+	var htmlOpts []renderer.Option
+	if hardWraps {
+		htmlOpts = append(htmlOpts, html.WithHardWraps())
+	}
+	if unsafe {
+		// Documents embed raw HTML.
+		htmlOpts = append(htmlOpts, html.WithUnsafe())
+	}
+
+	return &goldmarkRenderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(exts...),
+			goldmark.WithParserOptions(parserOpts...),
+			goldmark.WithRendererOptions(htmlOpts...),
+		),
+	}
+}
+
+func (r *goldmarkRenderer) Render(source []byte, lang string) (*Rendered, error) {
+	ctx := parser.NewContext(parser.WithIDs(newSlugIDs(lang)))
+	doc := r.md.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, source, doc); err != nil {
+		return nil, err
+	}
+	return &Rendered{HTML: buf.Bytes(), Headings: headingTree(doc, source)}, nil
+}
+
+// slugIDs generates heading IDs by analysis.Slug, unique in a document.
+type slugIDs struct {
+	lang string
+	used map[string]bool
+}
+
+func newSlugIDs(lang string) *slugIDs {
+	return &slugIDs{lang: lang, used: make(map[string]bool)}
+}
+
+func (s *slugIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	slug := analysis.Slug(s.lang, string(value))
+	id := slug
+	for i := 1; s.used[id]; i++ {
+		id = slug + "-" + strconv.Itoa(i)
+	}
+	s.used[id] = true
+	return []byte(id)
+}
+
+func (s *slugIDs) Put(value []byte) {
+	s.used[string(value)] = true
+}
+
+// headingTree returns headings of document that have IDs, nested by levels.
+func headingTree(doc ast.Node, source []byte) []*Heading {
+	var roots []*Heading
+	var stack []*Heading
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		id, ok := h.AttributeString("id")
+		if !ok || h.Level < tocMinLevel || h.Level > tocMaxLevel {
+			return ast.WalkSkipChildren, nil
+		}
+
+		idBytes, _ := id.([]byte)
+		heading := &Heading{
+			Level: h.Level,
+			Text:  nodeText(h, source),
+			ID:    string(idBytes),
+		}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, heading)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, heading)
+		}
+		stack = append(stack, heading)
+		return ast.WalkSkipChildren, nil
+	})
+	return roots
+}
+
+// nodeText returns text of inline children of n without markup.
+func nodeText(n ast.Node, source []byte) string {
+	var buf bytes.Buffer
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			buf.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.String()
+}
+
+var (
+	textParser     parser.Parser
+	textParserOnce sync.Once
+)
+
+// markdownText returns plain text of markdown source without rendering it,
+// which is much cheaper than rendering with highlighted code.
+func markdownText(source []byte) string {
+	textParserOnce.Do(func() {
+		textParser = goldmark.New(goldmark.WithExtensions(
+			extension.Table, extension.Strikethrough, extension.TaskList,
+			extension.Footnote, extension.DefinitionList),
+			goldmark.WithParserOptions(parser.WithAttribute())).Parser()
+	})
+
+	var buf bytes.Buffer
+	ast.Walk(textParser.Parse(text.NewReader(source)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			if n.Type() == ast.TypeBlock {
+				buf.WriteByte(' ')
+			}
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.Text:
+			buf.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(t.Value)
+		case *ast.CodeSpan:
+			buf.WriteString(nodeText(t, source))
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			for i := 0; i < t.Segments.Len(); i++ {
+				seg := t.Segments.At(i)
+				buf.WriteString(analysis.PlainText(string(seg.Value(source))))
+			}
+		case *ast.HTMLBlock, *ast.CodeBlock, *ast.FencedCodeBlock:
+			var raw bytes.Buffer
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				raw.Write(seg.Value(source))
+			}
+			if _, ok := n.(*ast.HTMLBlock); ok {
+				buf.WriteString(analysis.PlainText(raw.String()))
+			} else {
+				buf.Write(raw.Bytes())
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// blackfridayRenderer is the renderer used by older versions.
+type blackfridayRenderer struct{}
+
+func (blackfridayRenderer) Render(source []byte, lang string) (*Rendered, error) {
+	htmlFlags := 0
+	htmlFlags |= blackfriday.HTML_USE_XHTML
+	htmlFlags |= blackfriday.HTML_USE_SMARTYPANTS
+	htmlFlags |= blackfriday.HTML_SMARTYPANTS_FRACTIONS
+	htmlFlags |= blackfriday.HTML_SMARTYPANTS_LATEX_DASHES
+	htmlFlags |= blackfriday.HTML_GITHUB_BLOCKCODE
+	htmlFlags |= blackfriday.HTML_OMIT_CONTENTS
+	htmlRenderer := blackfriday.HtmlRenderer(htmlFlags, "", "")
+
+	// set up the parser
+	extensions := 0
+	extensions |= blackfriday.EXTENSION_NO_INTRA_EMPHASIS
+	extensions |= blackfriday.EXTENSION_TABLES
+	extensions |= blackfriday.EXTENSION_FENCED_CODE
+	extensions |= blackfriday.EXTENSION_AUTOLINK
+	extensions |= blackfriday.EXTENSION_STRIKETHROUGH
+	extensions |= blackfriday.EXTENSION_HARD_LINE_BREAK
+	extensions |= blackfriday.EXTENSION_SPACE_HEADERS
+	extensions |= blackfriday.EXTENSION_NO_EMPTY_LINE_BEFORE_BLOCK
+
+	return &Rendered{HTML: blackfriday.Markdown(source, htmlRenderer, extensions)}, nil
+}
+
+// renderMarkdown renders markdown source of document in given language
+// with renderer configured in app.conf.
+func renderMarkdown(raw []byte, lang string) *Rendered {
+	mdOnce.Do(initMarkdown)
+
+	r, err := mdRenderer.Render(raw, lang)
+	if err != nil {
+		beego.Error("models.renderMarkdown ->", err)
+		return new(Rendered)
+	}
+	return r
+}
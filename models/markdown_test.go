@@ -0,0 +1,251 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+var (
+	update = flag.Bool("update", false, "update golden files of markdown tests")
+	corpus = flag.String("corpus", "", "path to a checkout of beedoc to render every document of")
+)
+
+func testExtensions() []goldmark.Extender {
+	var exts []goldmark.Extender
+	for _, v := range strings.Split(defaultMarkdownExtensions, "|") {
+		exts = append(exts, markdownExtensions[v])
+	}
+	return exts
+}
+
+// testRenderer returns renderer of default settings in app.conf.
+func testRenderer() Renderer {
+	return newGoldmarkRenderer(append(testExtensions(), &codeHighlighter{lineNumbers: true}), true, true, true)
+}
+
+// TestMarkdownGolden renders documents of testdata/<lang>/, laid out as pages
+// of beedoc, and compares them with their *.golden.html, run with -update
+// to regenerate the golden files.
+// Code blocks are not highlighted, their markup depends on version of chroma.
+func TestMarkdownGolden(t *testing.T) {
+	r := newGoldmarkRenderer(testExtensions(), true, true, true)
+
+	var n int
+	err := filepath.Walk("testdata", func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(file) != ".md" {
+			return err
+		}
+		n++
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		_, body, state := splitFrontMatter(data)
+		if state != hasFrontMatter {
+			t.Errorf("%s: no front matter", file)
+			return nil
+		}
+
+		lang := strings.SplitN(filepath.ToSlash(file), "/", 3)[1]
+		rendered, err := r.Render(body, lang)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			return nil
+		}
+
+		golden := strings.TrimSuffix(file, ".md") + ".golden.html"
+		if *update {
+			return ioutil.WriteFile(golden, rendered.HTML, 0644)
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			return nil
+		}
+		if !bytes.Equal(rendered.HTML, want) {
+			t.Errorf("%s: output differs from %s:\n%s", file, golden, rendered.HTML)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("no documents in testdata")
+	}
+}
+
+// TestMarkdownCorpus parses and renders every document of beedoc checkout
+// given by -corpus, which has a directory of documents for each language.
+func TestMarkdownCorpus(t *testing.T) {
+	if len(*corpus) == 0 {
+		t.Skip("no -corpus given")
+	}
+
+	dirs, err := ioutil.ReadDir(*corpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := testRenderer()
+	for _, dir := range dirs {
+		if !dir.IsDir() || !strings.Contains(dir.Name(), "-") {
+			continue
+		}
+		root, err := ParseDocs(filepath.Join(*corpus, dir.Name()))
+		if err != nil {
+			t.Errorf("%s: %v", dir.Name(), err)
+			continue
+		}
+		for link, node := range root.links {
+			body, ok := node.source()
+			if !ok {
+				continue
+			}
+			rendered, err := r.Render(body, root.Lang)
+			if err != nil {
+				t.Errorf("%s/%s: %v", dir.Name(), link, err)
+				continue
+			}
+			if bytes.Contains(rendered.HTML, []byte("\n```")) {
+				t.Errorf("%s/%s: unclosed code block", dir.Name(), link)
+			}
+		}
+	}
+}
+
+var (
+	reHighlighted = regexp.MustCompile(`class="[^"]*\bhl\b[^"]*"`)
+	reLineNumber  = regexp.MustCompile(`class="ln"`)
+)
+
+// TestCodeHighlighter checks structure of highlighted code blocks,
+// tokens are left to chroma.
+func TestCodeHighlighter(t *testing.T) {
+	tests := []struct {
+		source      string
+		lineNumbers bool
+		highlighted int
+		numbers     int
+		contains    string
+	}{
+		{"```go {2-3}\na := 1\nb := 2\nc := 3\nd := 4\n```\n", true, 2, 4, "chroma"},
+		{"```go {1,4}\na := 1\nb := 2\nc := 3\nd := 4\n```\n", false, 2, 0, "chroma"},
+		{"```go {x,3-1}\na := 1\n```\n", true, 0, 1, "chroma"},
+		{"```shell\nbee run\n```\n", false, 0, 0, "bee"},
+		{"```unknown\n<b>&</b>\n```\n", false, 0, 0, "&lt;b&gt;&amp;&lt;/b&gt;"},
+		{"```\nplain\n```\n", false, 0, 0, "plain"},
+	}
+	for _, test := range tests {
+		r := newGoldmarkRenderer(append(testExtensions(), &codeHighlighter{lineNumbers: test.lineNumbers}), true, true, true)
+		rendered, err := r.Render([]byte(test.source), "en-US")
+		if err != nil {
+			t.Fatal(err)
+		}
+		html := string(rendered.HTML)
+		if !strings.HasPrefix(html, "<pre") {
+			t.Errorf("%q: not rendered as code block: %s", test.source, html)
+		}
+		if n := len(reHighlighted.FindAllString(html, -1)); n != test.highlighted {
+			t.Errorf("%q: %d lines highlighted, want %d", test.source, n, test.highlighted)
+		}
+		if n := len(reLineNumber.FindAllString(html, -1)); n != test.numbers {
+			t.Errorf("%q: %d line numbers, want %d", test.source, n, test.numbers)
+		}
+		if !strings.Contains(html, test.contains) {
+			t.Errorf("%q: output does not contain %q: %s", test.source, test.contains, html)
+		}
+	}
+}
+
+func TestParseFenceInfo(t *testing.T) {
+	tests := []struct {
+		info, lang string
+		ranges     [][2]int
+	}{
+		{"go", "go", nil},
+		{"Go {3-5}", "go", [][2]int{{3, 5}}},
+		{"go {3-5,8}", "go", [][2]int{{3, 5}, {8, 8}}},
+		{"{2}", "", [][2]int{{2, 2}}},
+		{"ini {a,5-2,7}", "ini", [][2]int{{7, 7}}},
+	}
+	for _, test := range tests {
+		lang, attrs := parseFenceInfo(test.info)
+		ranges := parseLineRanges(attrs)
+		if lang != test.lang || fmt.Sprint(ranges) != fmt.Sprint(test.ranges) {
+			t.Errorf("%q: got %q %v, want %q %v", test.info, lang, ranges, test.lang, test.ranges)
+		}
+	}
+}
+
+func TestMarkdownHeadings(t *testing.T) {
+	tests := []struct {
+		lang, source string
+		ids          []string
+	}{
+		{"en-US", "# Title\n## Basic router\n### Fixed router\n## Basic router\n", []string{"basic-router", "fixed-router", "basic-router-1"}},
+		{"en-US", "## Custom heading ID {#custom}\n", []string{"custom"}},
+		{"zh-CN", "## 基础路由\n## 注解路由 {#annotation}\n## 基础路由\n", []string{"section-426bca63", "annotation", "section-426bca63-1"}},
+	}
+
+	r := testRenderer()
+	for _, test := range tests {
+		rendered, err := r.Render([]byte(test.source), test.lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		var walk func([]*Heading)
+		walk = func(hs []*Heading) {
+			for _, h := range hs {
+				ids = append(ids, h.ID)
+				walk(h.Children)
+			}
+		}
+		walk(rendered.Headings)
+		if strings.Join(ids, " ") != strings.Join(test.ids, " ") {
+			t.Errorf("headings of %q: got %v, want %v", test.source, ids, test.ids)
+		}
+	}
+}
+
+func TestMarkdownText(t *testing.T) {
+	tests := []struct {
+		source, want string
+	}{
+		{"# Title\n\nSome *emphasis* and `code`.\nNext line.", "Title Some emphasis and code. Next line."},
+		{"## Heading {#custom}\n\n- [x] done\n- todo", "Heading done todo"},
+		{"```go {3}\nfunc main() {}\n```\n", "func main() {}"},
+		{"<div class=\"note\">Raw <b>HTML</b></div>\n\nText with <span>inline</span> tags.", "Raw HTML Text with inline tags."},
+		{"| A | B |\n|---|---|\n| 1 | 2 |\n", "A B 1 2"},
+		{"基础路由\n\n从 beego 1.2 版本开始支持。", "基础路由 从 beego 1.2 版本开始支持。"},
+	}
+	for _, test := range tests {
+		if got := markdownText([]byte(test.source)); got != test.want {
+			t.Errorf("markdownText(%q) = %q, want %q", test.source, got, test.want)
+		}
+	}
+}
@@ -16,6 +16,7 @@
 package models
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"os"
@@ -28,42 +29,18 @@ import (
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/toolbox"
 	"github.com/astaxie/beego/utils"
-	"github.com/slene/blackfriday"
 )
 
-var docs = make(map[string]*DocRoot)
-
+// oldDocNode descriables a file of documentation file structure tree.
 type oldDocNode struct {
 	Sha  string
 	Path string
 	Type string
-}
-
-// docTree descriables a documentation file structure tree.
-var docTree struct {
-	Tree []oldDocNode
-}
-
-var blogTree struct {
-	Tree []oldDocNode
-}
 
-var productTree struct {
-	Tree []oldDocNode
+	// Updated is when the file last changed upstream, as far as syncs have seen.
+	Updated time.Time
 }
 
-type docFile struct {
-	Title string
-	Data  []byte
-}
-
-var (
-	docLock  *sync.RWMutex
-	blogLock *sync.RWMutex
-	docMap   map[string]*docFile
-	blogMap  map[string]*docFile
-)
-
 var githubCred string
 
 func setGithubCredentials(id, secret string) {
@@ -71,7 +48,7 @@ func setGithubCredentials(id, secret string) {
 }
 
 func GetDocByLocale(lang string) *DocRoot {
-	return docs[lang]
+	return CurrentStore().DocRoot(lang)
 }
 
 func InitModels() {
@@ -79,12 +56,11 @@ func InitModels() {
 	setGithubCredentials(beego.AppConfig.String("github::client_id"),
 		beego.AppConfig.String("github::client_secret"))
 
-	docLock = new(sync.RWMutex)
-	blogLock = new(sync.RWMutex)
+	initSections()
 
-	parseDocs()
-	initMaps()
-	initProuctCase()
+	if err := initSnapshots(); err != nil {
+		beego.Error(err)
+	}
 
 	updateTask := toolbox.NewTask("check file update", "0 */5 * * * *", checkFileUpdates)
 
@@ -101,33 +77,26 @@ func InitModels() {
 	toolbox.StartTask()
 }
 
-func parseDocs() {
-	root, err := ParseDocs("docs/zh-CN")
-	if err != nil {
-		beego.Error(err)
-	}
-
-	if root != nil {
-		docs["zh-CN"] = root
-	}
-
-	root, err = ParseDocs("docs/en-US")
-	if err != nil {
-		beego.Error(err)
-	}
-
-	if root != nil {
-		docs["en-US"] = root
-	}
-
-	root, err = ParseDocs("docs/ru-RU")
-	if err != nil {
-		beego.Error(err)
-	}
+// parseDocs parses documentation of every language in given directory,
+// a language that has no directory is skipped.
+func parseDocs(dir string) (map[string]*DocRoot, error) {
+	roots := make(map[string]*DocRoot)
+	for _, lang := range strings.Split(beego.AppConfig.String("lang::types"), "|") {
+		langDir := path.Join(dir, lang)
+		if !utils.FileExists(langDir) {
+			continue
+		}
 
-	if root != nil {
-		docs["ru-RU"] = root
+		root, err := ParseDocs(langDir)
+		if err != nil {
+			return nil, err
+		}
+		if root.Doc == nil {
+			return nil, errors.New("no documentation found in " + langDir)
+		}
+		roots[lang] = root
 	}
+	return roots, nil
 }
 
 func needCheckUpdate() bool {
@@ -137,201 +106,42 @@ func needCheckUpdate() bool {
 		return true
 	}
 
-	if !utils.FileExists("conf/docTree.json") || !utils.FileExists("conf/blogTree.json") ||
-		!utils.FileExists("conf/productTree.json") {
-		return true
-	}
-
-	return time.Unix(stamp, 0).Add(5 * time.Minute).Before(time.Now())
-}
-
-func initDocMap() {
-	// Documentation names.
-	docNames := make([]string, 0, 20)
-	docNames = append(docNames, strings.Split(
-		beego.AppConfig.String("app::doc_names"), "|")...)
-
-	isConfExist := utils.FileExists("conf/docTree.json")
-	if isConfExist {
-		f, err := os.Open("conf/docTree.json")
-		if err != nil {
-			beego.Error("models.initDocMap -> load data:", err.Error())
-			return
-		}
-		defer f.Close()
-
-		d := json.NewDecoder(f)
-		if err = d.Decode(&docTree); err != nil {
-			beego.Error("models.initDocMap -> decode data:", err.Error())
-			return
-		}
-	} else {
-		// Generate 'docTree'.
-		for _, v := range docNames {
-			docTree.Tree = append(docTree.Tree, oldDocNode{Path: v})
+	for _, sec := range sections {
+		if !utils.FileExists(ContentPath(sec.TreeName)) {
+			return true
 		}
 	}
 
-	docLock.Lock()
-	defer docLock.Unlock()
-
-	docMap = make(map[string]*docFile)
-	langs := strings.Split(beego.AppConfig.String("lang::types"), "|")
-
-	os.Mkdir("docs", os.ModePerm)
-	for _, l := range langs {
-		os.Mkdir("docs/"+l, os.ModePerm)
-		for _, v := range docTree.Tree {
-			var fullName string
-			if isConfExist {
-				fullName = v.Path
-			} else {
-				fullName = l + "/" + v.Path
-			}
-
-			docMap[fullName] = getFile("docs/" + fullName)
-		}
-	}
+	return time.Unix(stamp, 0).Add(5 * time.Minute).Before(time.Now())
 }
 
-func initBlogMap() {
-	os.Mkdir("blog", os.ModePerm)
-	langs := strings.Split(beego.AppConfig.String("lang::types"), "|")
-	for _, l := range langs {
-		os.Mkdir("blog/"+l, os.ModePerm)
-	}
-
-	if !utils.FileExists("conf/blogTree.json") {
-		beego.Error("models.initBlogMap -> conf/blogTree.json does not exist")
-		return
-	}
-
-	f, err := os.Open("conf/blogTree.json")
+// loadTree decodes tree file of last sync.
+func loadTree(treeName string, v interface{}) error {
+	f, err := os.Open(treeName)
 	if err != nil {
-		beego.Error("models.initBlogMap -> load data:", err.Error())
-		return
+		return err
 	}
 	defer f.Close()
 
-	d := json.NewDecoder(f)
-	err = d.Decode(&blogTree)
-	if err != nil {
-		beego.Error("models.initBlogMap -> decode data:", err.Error())
-		return
-	}
-
-	blogLock.Lock()
-	defer blogLock.Unlock()
-
-	blogMap = make(map[string]*docFile)
-	for _, v := range blogTree.Tree {
-		blogMap[v.Path] = getFile("blog/" + v.Path)
-	}
-}
-
-func initMaps() {
-	initDocMap()
-	initBlogMap()
-	initProuctCase()
+	return json.NewDecoder(f).Decode(v)
 }
 
-// loadFile returns []byte of file data by given path.
-func loadFile(filePath string) ([]byte, error) {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return []byte(""), errors.New("Fail to open file: " + err.Error())
-	}
-
-	fi, err := f.Stat()
-	if err != nil {
-		return []byte(""), errors.New("Fail to get file information: " + err.Error())
-	}
-
-	d := make([]byte, fi.Size())
-	f.Read(d)
-	return d, nil
-}
-
-func markdown(raw []byte) []byte {
-	htmlFlags := 0
-	htmlFlags |= blackfriday.HTML_USE_XHTML
-	htmlFlags |= blackfriday.HTML_USE_SMARTYPANTS
-	htmlFlags |= blackfriday.HTML_SMARTYPANTS_FRACTIONS
-	htmlFlags |= blackfriday.HTML_SMARTYPANTS_LATEX_DASHES
-	htmlFlags |= blackfriday.HTML_GITHUB_BLOCKCODE
-	htmlFlags |= blackfriday.HTML_OMIT_CONTENTS
-	htmlFlags |= blackfriday.HTML_COMPLETE_PAGE
-	renderer := blackfriday.HtmlRenderer(htmlFlags, "", "")
-
-	// set up the parser
-	extensions := 0
-	extensions |= blackfriday.EXTENSION_NO_INTRA_EMPHASIS
-	extensions |= blackfriday.EXTENSION_TABLES
-	extensions |= blackfriday.EXTENSION_FENCED_CODE
-	extensions |= blackfriday.EXTENSION_AUTOLINK
-	extensions |= blackfriday.EXTENSION_STRIKETHROUGH
-	extensions |= blackfriday.EXTENSION_HARD_LINE_BREAK
-	extensions |= blackfriday.EXTENSION_SPACE_HEADERS
-	extensions |= blackfriday.EXTENSION_NO_EMPTY_LINE_BEFORE_BLOCK
-
-	body := blackfriday.Markdown(raw, renderer, extensions)
-	return body
-}
-
-func getFile(filePath string) *docFile {
-	if strings.Contains(filePath, "images") {
-		return nil
-	}
-
-	df := &docFile{}
-	p, err := loadFile(filePath + ".md")
-	if err != nil {
-		beego.Error("models.getFile -> ", err)
-		return nil
-	}
-
-	// Parse and render.
-	s := string(p)
-	i := strings.Index(s, "\n")
-	if i > -1 {
-		// Has title.
-		df.Title = strings.TrimSpace(
-			strings.Replace(s[:i+1], "#", "", -1))
-		if len(s) >= i+2 {
-			df.Data = []byte(strings.TrimSpace(s[i+2:]))
-		}
-	} else {
-		df.Data = p
+// loadTreeFile returns nodes of tree by given file name, which records
+// files of last sync. A missing or broken tree makes next sync fetch all files.
+func loadTreeFile(treeName string) []oldDocNode {
+	var t struct {
+		Tree []oldDocNode
 	}
-
-	df.Data = markdown(df.Data)
-	return df
-}
-
-// GetDoc returns 'docFile' by given name and language version.
-func GetDoc(fullName, lang string) *docFile {
-	filePath := "docs/" + lang + "/" + fullName
-
-	if beego.BConfig.RunMode == "dev" {
-		return getFile(filePath)
+	if err := loadTree(treeName, &t); err != nil && !os.IsNotExist(err) {
+		beego.Error("models.loadTreeFile -> load data:", err.Error())
 	}
-
-	docLock.RLock()
-	defer docLock.RUnlock()
-	return docMap[lang+"/"+fullName]
+	return t.Tree
 }
 
-// GetBlog returns 'docFile' by given name and language version.
-func GetBlog(fullName, lang string) *docFile {
-	filePath := "blog/" + lang + "/" + fullName
-
-	if beego.BConfig.RunMode == "dev" {
-		return getFile(filePath)
-	}
-
-	blogLock.RLock()
-	defer blogLock.RUnlock()
-	return blogMap[lang+"/"+fullName]
+// GetPage returns document of given section, "docs" or "blog", by its path
+// relative to the language directory without ".md".
+func GetPage(section, name, lang string) *DocNode {
+	return CurrentStore().Page(section, name, lang)
 }
 
 var checkTicker *time.Ticker
@@ -344,17 +154,18 @@ func checkTickerTimer(checkChan <-chan time.Time) {
 }
 
 type rawFile struct {
-	name   string
-	rawURL string
-	data   []byte
+	name string
+	path string
+	data []byte
+	err  error
 }
 
 func (rf *rawFile) Name() string {
 	return rf.name
 }
 
-func (rf *rawFile) RawUrl() string {
-	return rf.rawURL
+func (rf *rawFile) Path() string {
+	return rf.path
 }
 
 func (rf *rawFile) Data() []byte {
@@ -366,39 +177,65 @@ func (rf *rawFile) SetData(p []byte) {
 }
 
 func checkFileUpdates() error {
-	beego.Trace("Checking file updates")
-
-	type tree struct {
-		ApiUrl, RawUrl, TreeName, Prefix string
+	names := make([]string, len(sections))
+	for i, sec := range sections {
+		names[i] = sec.Name
 	}
+	return updateSections(names...)
+}
 
-	var trees = []*tree{
-		{
-			ApiUrl:   "https://api.github.com/repos/beego/beedoc/git/trees/master?recursive=1&" + githubCred,
-			RawUrl:   "https://raw.github.com/beego/beedoc/master/",
-			TreeName: "conf/docTree.json",
-			Prefix:   "docs/",
-		},
-		{
-			ApiUrl:   "https://api.github.com/repos/beego/beeblog/git/trees/master?recursive=1&" + githubCred,
-			RawUrl:   "https://raw.github.com/beego/beeblog/master/",
-			TreeName: "conf/blogTree.json",
-			Prefix:   "blog/",
-		},
-		{
-			ApiUrl:   "https://api.github.com/repos/beego/products/git/trees/master?recursive=1&" + githubCred,
-			RawUrl:   "https://raw.github.com/beego/products/master/",
-			TreeName: "conf/productTree.json",
-			Prefix:   "products/",
-		},
-	}
+// syncLock makes sure scheduled checks and webhooks never sync at the same time.
+var syncLock sync.Mutex
 
-	for _, tree := range trees {
-		var tmpTree struct {
-			Tree []*oldDocNode
+// updateSections checks file updates of sections by given names.
+// Changes are applied to a copy of the active snapshot, which replaces
+// the active one only when it has been loaded without errors.
+func updateSections(names ...string) (err error) {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+
+	beego.Trace("Checking file updates:", names)
+
+	timeout := time.Duration(beego.AppConfig.DefaultInt("fetch::timeout", 600)) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	next, err := newSnapshot()
+	if err != nil {
+		return errors.New("models.checkFileUpdates -> create snapshot: " + err.Error())
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(snapshotPath(next))
+		}
+	}()
+	nextDir := snapshotPath(next)
+
+	updated := false
+	var renames []*rename
+	var scopes []*cacheScope
+	for _, sec := range sections {
+		if !containsString(names, sec.Name) {
+			continue
+		}
+		prefix := path.Join(nextDir, sec.Prefix) + "/"
+		treeName := path.Join(nextDir, sec.TreeName)
+
+		// Sources that keep a local clone tell exactly what changed.
+		var changes *changeSet
+		if s, ok := sec.Source.(syncer); ok {
+			if changes, err = s.Sync(ctx); err != nil {
+				return errors.New("models.checkFileUpdates -> sync " + sec.Name + ": " + err.Error())
+			}
 		}
 
-		err := getHttpJson(tree.ApiUrl, &tmpTree)
+		// Unchanged tree means nothing to do, unless some files are still missing.
+		treeCtx, scope := withCacheScope(ctx, !sec.failed && utils.FileExists(treeName))
+		entries, err := sec.Source.Tree(treeCtx)
+		if err == errNotModified {
+			beego.Trace("Tree of", sec.Name, "not modified")
+			continue
+		}
 		if err != nil {
 			return errors.New("models.checkFileUpdates -> get trees: " + err.Error())
 		}
@@ -406,51 +243,64 @@ func checkFileUpdates() error {
 		var saveTree struct {
 			Tree []*oldDocNode
 		}
-		saveTree.Tree = make([]*oldDocNode, 0, len(tmpTree.Tree))
+		saveTree.Tree = make([]*oldDocNode, 0, len(entries))
+
+		// Times files last changed tell whether their translations are up to date.
+		synced := time.Now()
+		lastUpdated := make(map[string]time.Time)
+		for _, node := range savedTree(sec.Prefix) {
+			lastUpdated[node.Path] = node.Updated
+		}
 
 		// Compare SHA.
-		files := make([]*rawFile, 0, len(tmpTree.Tree))
-		for _, node := range tmpTree.Tree {
-			// Skip non-md files and "README.md".
-			if node.Type != "blob" || (!strings.HasSuffix(node.Path, ".md") &&
-				!strings.Contains(node.Path, "images") &&
-				!strings.HasSuffix(node.Path, ".json")) ||
-				strings.HasPrefix(strings.ToLower(node.Path), "readme") {
+		files := make([]*rawFile, 0, len(entries))
+		for _, node := range entries {
+			if node.Type != "blob" || !isContentFile(node.Path) {
 				continue
 			}
 
 			name := strings.TrimSuffix(node.Path, ".md")
+			updatedAt := lastUpdated[name]
 
-			if checkSHA(name, node.Sha, tree.Prefix) {
+			// SHA comparison is kept for synced sources as well,
+			// in case the saved tree and the clone went out of step.
+			if (changes != nil && changes.Changed[node.Path]) || checkSHA(name, node.Sha, sec.Prefix) {
 				beego.Info("Need to update:", name)
 				files = append(files, &rawFile{
-					name:   name,
-					rawURL: tree.RawUrl + node.Path,
+					name: name,
+					path: node.Path,
 				})
+				updatedAt = synced
 			}
 
 			saveTree.Tree = append(saveTree.Tree, &oldDocNode{
-				Path: name,
-				Sha:  node.Sha,
+				Path:    name,
+				Sha:     node.Sha,
+				Updated: updatedAt,
 			})
-			// For save purpose, reset name.
-			node.Path = name
 		}
 
-		// Fetch files.
-		if err := getFiles(files); err != nil {
-			return errors.New("models.checkFileUpdates -> fetch files: " + err.Error())
+		// Fetch files, failed ones keep their old content and SHA so they are retried next time.
+		if err := getFiles(ctx, sec.Source, files); err != nil {
+			if ctx.Err() != nil {
+				return errors.New("models.checkFileUpdates -> fetch files: " + ctx.Err().Error())
+			}
+			beego.Error("models.checkFileUpdates -> fetch files of "+sec.Name+":", err)
+			files, saveTree.Tree = dropFailedFiles(files, saveTree.Tree, sec.Prefix)
+			sec.failed = true
+		} else {
+			sec.failed = false
+			scopes = append(scopes, scope)
 		}
 
 		// Update data.
 		for _, f := range files {
-			os.MkdirAll(path.Join(tree.Prefix, path.Dir(f.name)), os.ModePerm)
-			suf := ".md"
-			if strings.Contains(f.name, "images") ||
-				strings.HasSuffix(f.name, ".json") {
-				suf = ""
-			}
-			fw, err := os.Create(tree.Prefix + f.name + suf)
+			os.MkdirAll(path.Join(prefix, path.Dir(f.name)), os.ModePerm)
+
+			// Files are hard links shared with older snapshots, never write in place.
+			name := contentFilePath(prefix, f.name)
+			os.Remove(name)
+			fw, err := os.Create(name)
 			if err != nil {
 				beego.Error("models.checkFileUpdates -> open file:", err.Error())
 				continue
@@ -464,42 +314,154 @@ func checkFileUpdates() error {
 			}
 		}
 
+		// Remove files deleted upstream, the previous tree tells what used to exist.
+		deleted := make(map[string]bool)
+		if changes != nil {
+			for _, p := range changes.Deleted {
+				if isContentFile(p) {
+					deleted[strings.TrimSuffix(p, ".md")] = true
+				}
+			}
+		}
+		current := make(map[string]string, len(saveTree.Tree))
+		for _, node := range saveTree.Tree {
+			current[node.Path] = node.Sha
+		}
+		for _, node := range savedTree(sec.Prefix) {
+			if _, ok := current[node.Path]; !ok {
+				deleted[node.Path] = true
+			}
+		}
+		for name := range deleted {
+			if _, ok := current[name]; ok {
+				continue
+			}
+			beego.Info("Need to delete:", name)
+			removeContentFile(prefix, name)
+		}
+
+		// A deleted file whose content shows up under a new name has been renamed.
+		renames = append(renames, findRenames(sec, deleted, files, current)...)
+
+		if len(files) == 0 && len(deleted) == 0 && utils.FileExists(treeName) {
+			continue
+		}
+		updated = true
+
 		// Save documentation information.
-		f, err := os.Create(tree.TreeName)
+		os.Remove(treeName)
+		f, err := os.Create(treeName)
 		if err != nil {
 			return errors.New("models.checkFileUpdates -> save data: " + err.Error())
 		}
 
 		e := json.NewEncoder(f)
 		err = e.Encode(&saveTree)
+		f.Close()
 		if err != nil {
 			return errors.New("models.checkFileUpdates -> encode data: " + err.Error())
 		}
-		f.Close()
 	}
 
-	beego.Trace("Finish check file updates")
-	parseDocs()
-	initMaps()
+	if !updated {
+		beego.Trace("Finish check file updates, nothing changed")
+		os.RemoveAll(nextDir)
+		commitCacheScopes(scopes)
+		return nil
+	}
+
+	snap, err := loadSnapshot(next)
+	if err != nil {
+		return errors.New("models.checkFileUpdates -> load snapshot " + next + ": " + err.Error())
+	}
+
+	// Links of renamed files are only known by parsed documents.
+	if len(renames) > 0 {
+		addRedirects(snap.redirects, renames,
+			resolveLinks(CurrentStore().docs, renames, func(r *rename) string { return r.oldName }),
+			resolveLinks(snap.docs, renames, func(r *rename) string { return r.newName }))
+		if err = saveRedirects(nextDir, snap.redirects); err != nil {
+			return errors.New("models.checkFileUpdates -> " + err.Error())
+		}
+	}
+
+	if err = activateSnapshot(snap); err != nil {
+		return errors.New("models.checkFileUpdates -> activate snapshot " + next + ": " + err.Error())
+	}
+	commitCacheScopes(scopes)
+
+	pruneSnapshots()
+	beego.Trace("Finish check file updates, snapshot", next, "is active")
 	return nil
 }
 
-// checkSHA returns true if the documentation file need to update.
-func checkSHA(name, sha, prefix string) bool {
-	var tree struct {
-		Tree []oldDocNode
+// dropFailedFiles removes files failed to fetch from files, and restores
+// their nodes in tree to the last sync, or removes new ones.
+func dropFailedFiles(files []*rawFile, tree []*oldDocNode, prefix string) ([]*rawFile, []*oldDocNode) {
+	failed := make(map[string]bool)
+	ok := files[:0]
+	for _, f := range files {
+		if f.err != nil {
+			failed[f.name] = true
+		} else {
+			ok = append(ok, f)
+		}
+	}
+
+	oldNodes := make(map[string]oldDocNode)
+	for _, node := range savedTree(prefix) {
+		oldNodes[node.Path] = node
+	}
+
+	nodes := tree[:0]
+	for _, node := range tree {
+		if failed[node.Path] {
+			old, exists := oldNodes[node.Path]
+			if !exists {
+				continue
+			}
+			node.Sha = old.Sha
+			node.Updated = old.Updated
+		}
+		nodes = append(nodes, node)
+	}
+	return ok, nodes
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
 	}
+	return false
+}
+
+// isContentFile returns true if the file of given upstream path should be mirrored,
+// which are markdown files except "README.md", images and JSON files.
+func isContentFile(p string) bool {
+	return (strings.HasSuffix(p, ".md") ||
+		strings.Contains(p, "images") ||
+		strings.HasSuffix(p, ".json")) &&
+		!strings.HasPrefix(strings.ToLower(p), "readme")
+}
 
-	switch prefix {
-	case "docs/":
-		tree = docTree
-	case "blog/":
-		tree = blogTree
-	default:
-		tree = productTree
+// contentFilePath returns local path of file by given section prefix and name.
+func contentFilePath(prefix, name string) string {
+	if strings.Contains(name, "images") || strings.HasSuffix(name, ".json") {
+		return prefix + name
 	}
+	return prefix + name + ".md"
+}
+
+// savedTree returns the tree of last sync of section by given prefix.
+func savedTree(prefix string) []oldDocNode {
+	return CurrentStore().tree(prefix)
+}
 
-	for _, v := range tree.Tree {
+// checkSHA returns true if the documentation file need to update.
+func checkSHA(name, sha, prefix string) bool {
+	for _, v := range savedTree(prefix) {
 		if v.Path == name {
 			// Found.
 			if v.Sha != sha {
@@ -512,3 +474,19 @@ func checkSHA(name, sha, prefix string) bool {
 	// Not found.
 	return true
 }
+
+// removeContentFile removes local file of given name and its parent
+// directories that become empty.
+func removeContentFile(prefix, name string) {
+	if err := os.Remove(contentFilePath(prefix, name)); err != nil && !os.IsNotExist(err) {
+		beego.Error("models.removeContentFile -> remove file:", err.Error())
+		return
+	}
+
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if os.Remove(path.Join(prefix, dir)) != nil {
+			// Not empty.
+			break
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"expvar"
+	"path"
+	"sync"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/utils"
+)
+
+const (
+	// DefaultLang is the language pages fall back to.
+	DefaultLang = "en-US"
+
+	// builtinPagesDir keeps copies of pages shipped with the site,
+	// which are served when synced content lacks them.
+	builtinPagesDir = "conf/pages"
+)
+
+var (
+	builtinPages     *DocRoot
+	builtinPagesOnce sync.Once
+
+	// missingPages counts requests of pages by "<lang>/<name>" that
+	// the language does not have.
+	missingPages = expvar.NewMap("missingpages")
+)
+
+func getBuiltinPages() *DocRoot {
+	builtinPagesOnce.Do(func() {
+		dir := path.Join(builtinPagesDir, DefaultLang)
+		if !utils.FileExists(dir) {
+			return
+		}
+
+		root, err := ParseDocs(dir)
+		if err != nil {
+			beego.Error("models.getBuiltinPages -> " + err.Error())
+			return
+		}
+		builtinPages = root
+	})
+	return builtinPages
+}
+
+// FindPage returns page of documentation by given name and language version.
+// A page the language does not have falls back to languages of FallbackLangs,
+// and then to the built-in copy. It returns language of the page found,
+// or nil when there is none.
+func FindPage(name, lang string) (*DocNode, string) {
+	page := GetPage("docs", name, lang)
+	if page != nil && len(page.Fallback) == 0 {
+		return page, lang
+	}
+	missingPages.Add(lang+"/"+name, 1)
+
+	if page != nil {
+		beego.Warn("models.FindPage -> " + lang + "/" + name + " does not exist, use " + page.Fallback)
+		return page, page.Fallback
+	}
+
+	if root := getBuiltinPages(); root != nil {
+		if page, ok := root.GetPage(name); ok {
+			beego.Warn("models.FindPage -> " + name + " does not exist, use built-in copy")
+			return page, DefaultLang
+		}
+	}
+
+	beego.Error("models.FindPage -> " + lang + "/" + name + " does not exist")
+	return nil, ""
+}
@@ -15,19 +15,24 @@
 package models
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/astaxie/beego"
+
+	"github.com/beego/beeweb/analysis"
+)
+
+const (
+	summaryLength = 200
+	keywordCount  = 10
 )
 
 type DocList []*DocNode
@@ -37,7 +42,7 @@ func (s DocList) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s DocList) Less(i, j int) bool { return s[i].Sort < s[j].Sort }
 
 type DocNode struct {
-	root        bool
+	standalone  bool
 	IsDir       bool
 	Path        string
 	RelPath     string
@@ -47,6 +52,18 @@ type DocNode struct {
 	Name        string
 	Sort        int
 	Link        string
+	Summary     string
+	Keywords    []string
+	Description string
+	Abstract    string // Summary written in front matter, preferred to the generated one.
+	Tags        []string
+	Authors     []string
+	Aliases     []string
+	Draft       bool
+	Weight      int
+	Since       string
+	Extra       map[string]interface{}
+	Fallback    string // Language the document is taken from when it is not translated.
 	Docs        DocList
 	dirs        map[string]*DocNode
 	Root        *DocRoot
@@ -62,52 +79,92 @@ func (d *DocNode) HasContent() bool {
 }
 
 func (d *DocNode) GetContent() string {
+	return string(d.Render().HTML)
+}
+
+var reParagraph = regexp.MustCompile(`(?s)<p>(.*?)</p>`)
+
+// Lead returns plain text of the first paragraph of the document,
+// shortened to summaryLength.
+func (d *DocNode) Lead() string {
+	for _, m := range reParagraph.FindAllSubmatch(d.Render().HTML, -1) {
+		if text := analysis.PlainText(string(m[1])); len(text) > 0 {
+			return analysis.Summary(d.Root.Lang, text, summaryLength)
+		}
+	}
+	return ""
+}
+
+// Render returns rendered content of the document with its table of contents.
+// Results are cached until the file changes.
+func (d *DocNode) Render() *Rendered {
 	if !d.HasContent() {
-		return ""
+		return new(Rendered)
 	}
 
-	body, err := ioutil.ReadFile(d.FilePath)
+	fi, err := os.Stat(d.FilePath)
 	if err != nil {
-		return ""
+		return new(Rendered)
 	}
 
-	if i := bytes.Index(body, []byte("---")); i != -1 {
-		body = body[i+3:]
-		if i = bytes.Index(body, []byte("---")); i != -1 {
-			body = body[i+3:]
-			i = 0
-			m := 0
-		mFor:
-			for {
-				if len(body) > 0 {
-					if body[0] == ' ' || body[0] == '\n' {
-						if body[0] == '\n' {
-							m += 1
-						}
-						if m == 2 {
-							break mFor
-						}
-					} else {
-						break mFor
-					}
-					body = body[1:]
-				} else {
-					break mFor
-				}
-			}
+	cache := getRenderCache()
+	key := renderKey(d.FilePath, fi)
+	if r, ok := cache.get(key); ok {
+		return r
+	}
 
-			return string(markdown(body))
-		}
+	r := d.render()
+	cache.put(key, d.FilePath, r)
+	return r
+}
+
+func (d *DocNode) render() *Rendered {
+	body, ok := d.source()
+	if !ok {
+		return new(Rendered)
 	}
+	return renderMarkdown(body, d.Root.Lang)
+}
 
-	return ""
+// source returns markdown body of the document without its title or front matter.
+func (d *DocNode) source() ([]byte, bool) {
+	data, err := ioutil.ReadFile(d.FilePath)
+	if err != nil {
+		return nil, false
+	}
+
+	if d.standalone {
+		_, body := splitTitle(data)
+		return body, true
+	}
+
+	if _, body, state := splitFrontMatter(data); state == hasFrontMatter {
+		return bytes.TrimLeft(body, " \r\n"), true
+	}
+	return nil, false
+}
+
+// plainText returns text of the document without markup, taken from its
+// markdown so the document need not be rendered.
+func (d *DocNode) plainText() string {
+	if !d.HasContent() {
+		return ""
+	}
+	body, ok := d.source()
+	if !ok {
+		return ""
+	}
+	return markdownText(body)
 }
 
 type DocRoot struct {
-	Wd    string
-	Path  string
-	Doc   *DocNode
-	links map[string]*DocNode
+	Wd      string
+	Path    string
+	Lang    string
+	Doc     *DocNode
+	links   map[string]*DocNode
+	aliases map[string]*DocNode
+	pages   map[string]*DocNode
 }
 
 func (d *DocRoot) GetNodeByLink(link string) (*DocNode, bool) {
@@ -115,6 +172,19 @@ func (d *DocRoot) GetNodeByLink(link string) (*DocNode, bool) {
 	return n, ok
 }
 
+// GetPage returns document by its path relative to root without ".md",
+// including standalone pages that are not listed in the tree.
+func (d *DocRoot) GetPage(name string) (*DocNode, bool) {
+	n, ok := d.pages[name]
+	return n, ok
+}
+
+// GetNodeByAlias returns node that lists given link in its aliases.
+func (d *DocRoot) GetNodeByAlias(link string) (*DocNode, bool) {
+	n, ok := d.aliases[link]
+	return n, ok
+}
+
 func (d *DocRoot) walkParse() error {
 	var err error
 	if d.Path, err = filepath.Abs(d.Path); err != nil {
@@ -124,6 +194,7 @@ func (d *DocRoot) walkParse() error {
 	defer func() {
 		if err == nil {
 			d.sortAll(d.Doc)
+			d.analyze()
 		}
 	}()
 
@@ -142,6 +213,35 @@ func (d *DocRoot) sortAll(node *DocNode) {
 	}
 }
 
+// analyze generates summary and keywords of every node that has content
+// from its markdown, documents are rendered into cache only when
+// "cache::preload" is true.
+func (d *DocRoot) analyze() {
+	preload := beego.AppConfig.DefaultBool("cache::preload", true)
+	nodes := make([]*DocNode, 0, len(d.links)+len(d.pages))
+	for _, node := range d.links {
+		nodes = append(nodes, node)
+	}
+	for _, node := range d.pages {
+		if node.standalone {
+			nodes = append(nodes, node)
+		}
+	}
+
+	for _, node := range nodes {
+		if !node.HasContent() {
+			continue
+		}
+		if preload {
+			node.Render()
+		}
+
+		text := node.plainText()
+		node.Summary = analysis.Summary(d.Lang, text, summaryLength)
+		node.Keywords = analysis.Keywords(d.Lang, text, keywordCount)
+	}
+}
+
 func (d *DocRoot) makeDirNode(path string) error {
 	relPath, _ := filepath.Rel(d.Path, path)
 
@@ -191,126 +291,142 @@ func (d *DocRoot) getDirNode(path string) *DocNode {
 }
 
 func (d *DocRoot) makeFileNode(path string) error {
-	file, err := os.Open(path)
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+
+	meta, body, state := splitFrontMatter(data)
+	if state != hasFrontMatter {
+		if filepath.Ext(path) != ".md" {
+			// Images and other files.
+			return nil
+		}
+		if state == unclosedFrontMatter {
+			return fmt.Errorf("document %s has unclosed front matter", path)
+		}
+		return d.makePageNode(path, data)
+	}
+	fm := parseFrontMatter(path, meta)
+	if fm.Draft && beego.BConfig.RunMode != "dev" {
+		return nil
+	}
 
 	relPath, _ := filepath.Rel(d.Path, path)
 	relPath = strings.Replace(relPath, "\\", "/", -1)
 
 	docDir := d.getDirNode(filepath.Dir(relPath))
 
-	var bingo bool
-	var doc *DocNode
-	rd := bufio.NewReader(file)
-	no := 0
-	for {
-		line, _, err := rd.ReadLine()
-		if err == io.EOF {
-			break
+	doc := new(DocNode)
+	if fm.Root {
+		if len(docDir.FilePath) > 0 {
+			return fmt.Errorf("node %s has a document %s, can not replicate by %s",
+				docDir.Path, docDir.FilePath, path)
 		}
+		doc = docDir
+	} else {
+		doc.Path = path
+		doc.Root = d
+		doc.Parent = docDir
+	}
 
-		if no > 3 && !bingo {
-			break
+	doc.Name = fm.Name
+	doc.Link = fm.Link
+	doc.Sort = fm.Sort
+	doc.Description = fm.Description
+	doc.Abstract = fm.Summary
+	doc.Tags = fm.Tags
+	doc.Authors = fm.Authors
+	doc.Aliases = fm.Aliases
+	doc.Draft = fm.Draft
+	doc.Weight = fm.Weight
+	doc.Since = fm.Since
+	doc.Extra = fm.Extra
+	if len(fm.Date) > 0 {
+		if doc.Date, err = parseDate(fm.Date); err != nil {
+			return fmt.Errorf("document %s has invalid date: %v", path, err)
 		}
+	}
 
-		if no > 20 && bingo {
-			return fmt.Errorf("document %s not contained ended tag `---`", path)
+	if fm.Root {
+		// Directory has content only when there is something after front matter.
+		if len(bytes.TrimSpace(body)) > 0 {
+			doc.FilePath = path
+		}
+		if len(doc.Link) == 0 {
+			doc.Link = doc.RelPath + "/"
+		}
+		doc.FileRelPath = relPath
+	} else {
+		doc.RelPath = relPath
+		doc.FilePath = path
+		if len(doc.Link) == 0 {
+			doc.Link = doc.RelPath
 		}
 
-		data := string(bytes.TrimSpace(line))
-
-		if len(data) == 3 && data == "---" {
-
-			if bingo {
-				if doc.root {
-					if len(docDir.FilePath) > 0 {
-						return fmt.Errorf("node %s has a document %s, can not replicate by %s",
-							docDir.Path, docDir.FilePath, path)
-					}
-
-					docDir.Name = doc.Name
-					docDir.Date = doc.Date
-					docDir.Link = doc.Link
-					docDir.Sort = doc.Sort
-
-				mFor:
-					for {
-						l, _, er := rd.ReadLine()
-						if er != nil {
-							break mFor
-						}
-						if len(bytes.TrimSpace(l)) > 0 {
-							docDir.FilePath = path
-							break mFor
-						}
-					}
-
-					if len(docDir.Link) == 0 {
-						docDir.Link = docDir.RelPath + "/"
-					}
-
-					docDir.FileRelPath = relPath
-
-					doc = docDir
-				} else {
-					doc.RelPath = relPath
-					doc.FilePath = path
-					if len(doc.Link) == 0 {
-						doc.Link = doc.RelPath
-						// doc.Link = strings.TrimSuffix(doc.RelPath, filepath.Ext(doc.RelPath))
-					}
-
-					docDir.Docs = append(docDir.Docs, doc)
-				}
-
-				if dc, ok := d.links[doc.Link]; ok {
-					return fmt.Errorf("document %s's link %s is already used by %s", path, doc.Link, dc.Path)
-				}
-
-				d.links[doc.Link] = doc
-
-				break
-			}
+		docDir.Docs = append(docDir.Docs, doc)
+	}
 
-			doc = new(DocNode)
-			doc.Path = path
-			doc.Root = d
-			doc.Parent = docDir
+	if dc, ok := d.links[doc.Link]; ok {
+		return fmt.Errorf("document %s's link %s is already used by %s", path, doc.Link, dc.Path)
+	}
+	if dc, ok := d.aliases[doc.Link]; ok {
+		return fmt.Errorf("document %s's link %s is already used by %s", path, doc.Link, dc.Path)
+	}
+	d.links[doc.Link] = doc
 
-			bingo = true
-		}
+	d.pages[strings.TrimSuffix(relPath, ".md")] = doc
 
-		if bingo {
-			parts := strings.SplitN(data, ":", 2)
-			if len(parts) == 2 {
-				name := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
-				switch name {
-				case "root":
-					doc.root, _ = strconv.ParseBool(value)
-				case "name":
-					doc.Name = value
-				case "date":
-					doc.Date, err = beego.DateParse(value, "Y-m-d H:i")
-					if err != nil {
-						return err
-					}
-				case "link":
-					doc.Link = value
-				case "sort":
-					n, _ := strconv.ParseInt(value, 10, 64)
-					doc.Sort = int(n)
-				}
-			}
+	for _, alias := range doc.Aliases {
+		alias = strings.TrimPrefix(alias, "/docs/")
+		if dc, ok := d.links[alias]; ok {
+			return fmt.Errorf("document %s's alias %s is already used by %s", path, alias, dc.Path)
+		}
+		if dc, ok := d.aliases[alias]; ok {
+			return fmt.Errorf("document %s's alias %s is already used by %s", path, alias, dc.Path)
 		}
+		d.aliases[alias] = doc
+	}
+
+	return nil
+}
+
+// makePageNode makes node of a standalone page, which is a markdown file
+// without front matter and has its title in the first line.
+// Pages are not listed in the tree.
+func (d *DocRoot) makePageNode(path string, data []byte) error {
+	relPath, _ := filepath.Rel(d.Path, path)
+	relPath = strings.Replace(relPath, "\\", "/", -1)
+
+	doc := &DocNode{
+		standalone: true,
+		Path:       path,
+		RelPath:    relPath,
+		FilePath:   path,
+		Root:       d,
+		Parent:     d.getDirNode(filepath.Dir(relPath)),
 	}
+	doc.Name, _ = splitTitle(data)
 
+	d.pages[strings.TrimSuffix(relPath, ".md")] = doc
 	return nil
 }
 
+// reClosingHashes matches optional closing sequence of an ATX heading.
+var reClosingHashes = regexp.MustCompile(`\s+#+$`)
+
+// splitTitle returns title in the first line of a standalone page and the rest of it.
+func splitTitle(data []byte) (string, []byte) {
+	i := bytes.IndexByte(data, '\n')
+	if i == -1 {
+		return "", data
+	}
+
+	// Strip ATX heading markers only so that "C#" survives.
+	title := strings.TrimSpace(strings.TrimLeft(string(data[:i]), "#"))
+	return reClosingHashes.ReplaceAllString(title, ""), bytes.TrimSpace(data[i+1:])
+}
+
 func (d *DocRoot) walk(path string, info os.FileInfo, err error) error {
 	if err != nil {
 		return filepath.SkipDir
@@ -334,7 +450,10 @@ func (d *DocRoot) walk(path string, info os.FileInfo, err error) error {
 func ParseDocs(path string) (*DocRoot, error) {
 	root := new(DocRoot)
 	root.Path = path
+	root.Lang = filepath.Base(path)
 	root.links = make(map[string]*DocNode)
+	root.aliases = make(map[string]*DocNode)
+	root.pages = make(map[string]*DocNode)
 
 	if err := root.walkParse(); err == nil {
 		return root, err
@@ -2,9 +2,10 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
+	"path"
 
-	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/utils"
 )
 
@@ -22,48 +23,44 @@ type Project struct {
 	Date      string
 }
 
-var Products = new(products)
+// GetProducts returns product cases being served.
+func GetProducts() *products {
+	return CurrentStore().Products()
+}
 
-func initProuctCase() {
-	if !utils.FileExists("conf/productTree.json") {
-		beego.Error("models.initBlogMap -> conf/productTree.json does not exist")
-		return
+// loadProducts loads product cases of snapshot in given directory.
+func loadProducts(dir string, tree *[]oldDocNode) (*products, error) {
+	treeName := path.Join(dir, "productTree.json")
+	if !utils.FileExists(treeName) {
+		return nil, errors.New("models.loadProducts -> " + treeName + " does not exist")
 	}
 
-	f, err := os.Open("conf/productTree.json")
-	if err != nil {
-		beego.Error("models.initBlogMap -> load data:", err.Error())
-		return
+	var t struct {
+		Tree []oldDocNode
 	}
-	defer f.Close()
-
-	d := json.NewDecoder(f)
-	err = d.Decode(&productTree)
-	if err != nil {
-		beego.Error("models.initBlogMap -> decode data:", err.Error())
-		return
+	if err := loadTree(treeName, &t); err != nil {
+		return nil, errors.New("models.loadProducts -> decode data: " + err.Error())
 	}
+	*tree = t.Tree
 
-	fileName := "products/projects.json"
-
-	aProducts := *Products
+	fileName := path.Join(dir, "products/projects.json")
 
-	var file *os.File
+	aProducts := new(products)
 
-	if file, err = os.Open(fileName); err != nil {
-		beego.Error("open %s, %s", fileName, err.Error())
-		return
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, errors.New("models.loadProducts -> open " + fileName + ": " + err.Error())
 	}
+	defer file.Close()
 
-	d = json.NewDecoder(file)
-	if err = d.Decode(&aProducts); err != nil {
-		beego.Error("open %s, %s", fileName, err.Error())
-		return
+	d := json.NewDecoder(file)
+	if err = d.Decode(aProducts); err != nil {
+		return nil, errors.New("models.loadProducts -> decode " + fileName + ": " + err.Error())
 	}
 
 	for i, j := 0, len(aProducts.Projects)-1; i < j; i, j = i+1, j-1 {
 		aProducts.Projects[i], aProducts.Projects[j] = aProducts.Projects[j], aProducts.Projects[i]
 	}
 
-	*Products = aProducts
+	return aProducts, nil
 }
@@ -0,0 +1,172 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/utils"
+)
+
+// redirectsFile keeps redirects of renamed files in a snapshot, so that
+// a rollback restores redirects along with the files they point to.
+const redirectsFile = "redirects.json"
+
+// legacyRedirectsFile kept redirects before they were part of snapshots.
+const legacyRedirectsFile = "conf/redirects.json"
+
+// rename records a file moved upstream without changing its content.
+type rename struct {
+	section          string
+	lang             string
+	oldName, newName string
+}
+
+// findRenames pairs deleted files with fetched new files of the same content.
+func findRenames(sec *contentSection, deleted map[string]bool, files []*rawFile, current map[string]string) []*rename {
+	if sec.Name != "docs" && sec.Name != "blog" ||
+		!beego.AppConfig.DefaultBool("app::rename_redirects", false) {
+		return nil
+	}
+
+	oldSha := make(map[string]string)
+	for _, node := range savedTree(sec.Prefix) {
+		oldSha[node.Path] = node.Sha
+	}
+
+	added := make(map[string]string)
+	for _, f := range files {
+		if _, ok := oldSha[f.name]; !ok {
+			added[current[f.name]] = f.name
+		}
+	}
+
+	var renames []*rename
+	for name := range deleted {
+		newName, ok := added[oldSha[name]]
+		if !ok || strings.Contains(name, "images") || strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		lang := strings.SplitN(name, "/", 2)[0]
+		if lang != strings.SplitN(newName, "/", 2)[0] {
+			continue
+		}
+
+		beego.Info("Renamed:", name, "->", newName)
+		renames = append(renames, &rename{
+			section: sec.Name,
+			lang:    lang,
+			oldName: name,
+			newName: newName,
+		})
+	}
+	return renames
+}
+
+// resolveLinks returns URLs of renamed files in roots by names chosen with fn.
+func resolveLinks(roots map[string]*DocRoot, renames []*rename, fn func(*rename) string) map[*rename]string {
+	links := make(map[*rename]string, len(renames))
+	for _, r := range renames {
+		name := fn(r)
+		if r.section == "blog" {
+			links[r] = "/blog/" + path.Base(name)
+			continue
+		}
+
+		root := roots[r.lang]
+		if root == nil {
+			continue
+		}
+		rel := strings.TrimPrefix(name, r.lang+"/") + ".md"
+		for link, node := range root.links {
+			if node.RelPath == rel || (node.IsDir && node.FileRelPath == rel) {
+				links[r] = "/docs/" + link
+				break
+			}
+		}
+	}
+	return links
+}
+
+// addRedirects adds redirects from old to new links of renamed files to redirects.
+func addRedirects(redirects map[string]map[string]string, renames []*rename, oldLinks, newLinks map[*rename]string) {
+	for _, r := range renames {
+		from, to := oldLinks[r], newLinks[r]
+		if len(from) == 0 || len(to) == 0 || from == to {
+			continue
+		}
+
+		m := redirects[r.lang]
+		if m == nil {
+			m = make(map[string]string)
+			redirects[r.lang] = m
+		}
+
+		// Keep chains short and never redirect away from existing content.
+		for k, v := range m {
+			if v == from {
+				m[k] = to
+			}
+		}
+		delete(m, to)
+		m[from] = to
+	}
+}
+
+// saveRedirects writes redirects into snapshot in given directory. The file is
+// replaced rather than rewritten, it may be hard linked by other snapshots.
+func saveRedirects(dir string, redirects map[string]map[string]string) error {
+	data, err := json.Marshal(redirects)
+	if err != nil {
+		return errors.New("models.saveRedirects -> encode data: " + err.Error())
+	}
+
+	name := path.Join(dir, redirectsFile)
+	if err = ioutil.WriteFile(name+".tmp", data, 0644); err != nil {
+		return errors.New("models.saveRedirects -> save data: " + err.Error())
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+// loadRedirects reads redirects of snapshot in given directory.
+func loadRedirects(dir string) (map[string]map[string]string, error) {
+	redirects := make(map[string]map[string]string)
+	name := path.Join(dir, redirectsFile)
+	if !utils.FileExists(name) {
+		return redirects, nil
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return redirects, errors.New("models.loadRedirects -> load data: " + err.Error())
+	}
+	if err = json.Unmarshal(data, &redirects); err != nil {
+		return redirects, errors.New("models.loadRedirects -> decode data: " + err.Error())
+	}
+	return redirects, nil
+}
+
+// GetRedirect returns new URL of a renamed document or blog post.
+func GetRedirect(lang, url string) (string, bool) {
+	to, ok := CurrentStore().redirects[lang][url]
+	return to, ok
+}
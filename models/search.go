@@ -0,0 +1,298 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/astaxie/beego"
+
+	"github.com/beego/beeweb/analysis"
+)
+
+const (
+	searchTitleBoost   = 5
+	searchSnippetRunes = 160
+)
+
+// SearchResult represents a single ranked hit of a full-text search.
+type SearchResult struct {
+	Kind    string  `json:"kind"`
+	Lang    string  `json:"lang"`
+	Title   string  `json:"title"`
+	Link    string  `json:"link"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+type searchDoc struct {
+	kind  string
+	lang  string
+	title string
+	link  string
+	text  string
+}
+
+type posting struct {
+	doc int
+	tf  int
+}
+
+// searchIndex is an in-memory inverted index over docs and blog posts.
+// Terms are produced by the tokenizer of the document language.
+type searchIndex struct {
+	docs     []*searchDoc
+	langs    map[string]bool
+	postings map[string][]posting
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		langs:    make(map[string]bool),
+		postings: make(map[string][]posting),
+	}
+}
+
+func (idx *searchIndex) add(doc *searchDoc) {
+	id := len(idx.docs)
+	idx.docs = append(idx.docs, doc)
+	idx.langs[doc.lang] = true
+
+	freq := make(map[string]int)
+	for _, t := range analysis.IndexTerms(doc.lang, doc.title) {
+		freq[t] += searchTitleBoost
+	}
+	for _, t := range analysis.IndexTerms(doc.lang, doc.text) {
+		freq[t]++
+	}
+
+	for t, n := range freq {
+		idx.postings[t] = append(idx.postings[t], posting{doc: id, tf: n})
+	}
+}
+
+// search returns all documents containing every term of query, ranked by TF-IDF.
+// Query is analyzed separately for every language because tokenizers differ.
+func (idx *searchIndex) search(query, lang string) []*SearchResult {
+	var results []*SearchResult
+	for l := range idx.langs {
+		if len(lang) == 0 || l == lang {
+			results = append(results, idx.searchLang(analysis.Terms(l, query), l)...)
+		}
+	}
+
+	sort.Sort(searchResults(results))
+	return results
+}
+
+func (idx *searchIndex) searchLang(terms []string, lang string) []*SearchResult {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	scores := make(map[int]float64)
+	matched := make(map[int]int)
+	for _, t := range terms {
+		list := idx.postings[t]
+		if len(list) == 0 {
+			return nil
+		}
+
+		idf := math.Log(1 + float64(len(idx.docs))/float64(len(list)))
+		for _, p := range list {
+			if idx.docs[p.doc].lang != lang {
+				continue
+			}
+			scores[p.doc] += (1 + math.Log(float64(p.tf))) * idf
+			matched[p.doc]++
+		}
+	}
+
+	results := make([]*SearchResult, 0, len(scores))
+	for id, score := range scores {
+		if matched[id] < len(terms) {
+			continue
+		}
+
+		doc := idx.docs[id]
+		results = append(results, &SearchResult{
+			Kind:    doc.kind,
+			Lang:    doc.lang,
+			Title:   doc.title,
+			Link:    doc.link,
+			Snippet: highlightSnippet(doc.text, terms),
+			Score:   score,
+		})
+	}
+	return results
+}
+
+type searchResults []*SearchResult
+
+func (s searchResults) Len() int      { return len(s) }
+func (s searchResults) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s searchResults) Less(i, j int) bool {
+	if s[i].Score == s[j].Score {
+		return s[i].Link < s[j].Link
+	}
+	return s[i].Score > s[j].Score
+}
+
+// foldSnippet folds letters as tokenizers do for terms, keeping byte offsets,
+// e.g. Cyrillic 'ё' to 'е'.
+func foldSnippet(s string) string {
+	return strings.Replace(s, "ё", "е", -1)
+}
+
+// highlightSnippet returns an HTML escaped excerpt of text around the first
+// matched term, with every matched term wrapped in <mark>.
+func highlightSnippet(text string, terms []string) string {
+	lower := foldSnippet(strings.ToLower(text))
+	if len(lower) != len(text) {
+		// Case folding changed byte offsets, do not try to highlight.
+		lower = text
+	}
+	folded := make([]string, len(terms))
+	for i, t := range terms {
+		folded[i] = foldSnippet(t)
+	}
+	terms = folded
+
+	first := -1
+	for _, t := range terms {
+		if i := strings.Index(lower, t); i > -1 && (first == -1 || i < first) {
+			first = i
+		}
+	}
+	if first == -1 {
+		first = 0
+	}
+
+	// Compute window in runes.
+	runes := []rune(text)
+	start := len([]rune(text[:first])) - searchSnippetRunes/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + searchSnippetRunes
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	window := string(runes[start:end])
+	lowerWindow := string([]rune(lower)[start:end])
+	if len(lowerWindow) != len(window) {
+		lowerWindow = window
+	}
+
+	// Mark ranges of matched terms.
+	marks := make([]bool, len(window)+1)
+	for _, t := range terms {
+		for i := 0; ; {
+			j := strings.Index(lowerWindow[i:], t)
+			if j == -1 {
+				break
+			}
+			for k := i + j; k < i+j+len(t); k++ {
+				marks[k] = true
+			}
+			i += j + len(t)
+		}
+	}
+
+	var buf strings.Builder
+	if start > 0 {
+		buf.WriteString("…")
+	}
+	inMark := false
+	last := 0
+	for i := range window {
+		if marks[i] != inMark {
+			buf.WriteString(html.EscapeString(window[last:i]))
+			if inMark {
+				buf.WriteString("</mark>")
+			} else {
+				buf.WriteString("<mark>")
+			}
+			inMark = marks[i]
+			last = i
+		}
+	}
+	buf.WriteString(html.EscapeString(window[last:]))
+	if inMark {
+		buf.WriteString("</mark>")
+	}
+	if end < len(runes) {
+		buf.WriteString("…")
+	}
+	return buf.String()
+}
+
+// buildSearchIndex indexes every documentation node of all locales and all blog posts in given store.
+func buildSearchIndex(s *ContentStore) *searchIndex {
+	idx := newSearchIndex()
+
+	for lang, root := range s.docs {
+		for link, node := range root.links {
+			if !node.HasContent() || len(node.Fallback) > 0 {
+				continue
+			}
+			idx.add(&searchDoc{
+				kind:  "docs",
+				lang:  lang,
+				title: node.Name,
+				link:  "/" + lang + "/docs/" + link,
+				text:  node.plainText(),
+			})
+		}
+	}
+
+	for lang, root := range s.blogs {
+		for name, node := range root.pages {
+			if len(node.Fallback) > 0 {
+				continue
+			}
+			idx.add(&searchDoc{
+				kind:  "blog",
+				lang:  lang,
+				title: node.Name,
+				link:  "/" + lang + "/blog/" + name,
+				text:  node.plainText(),
+			})
+		}
+	}
+
+	beego.Info("Search index built:", len(idx.docs), "documents")
+	return idx
+}
+
+// Search returns ranked results for query, filtered by language when lang is not empty,
+// and the total number of matched documents.
+func Search(query, lang string, limit, offset int) ([]*SearchResult, int) {
+	results := CurrentStore().search.search(query, lang)
+	total := len(results)
+
+	if offset >= total {
+		return nil, total
+	}
+	results = results[offset:]
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	return results, total
+}
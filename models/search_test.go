@@ -0,0 +1,122 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/astaxie/beego"
+
+	"github.com/beego/beeweb/analysis"
+)
+
+func TestSearchCJK(t *testing.T) {
+	idx := newSearchIndex()
+	idx.add(&searchDoc{kind: "docs", lang: "zh-CN", title: "路由设置", link: "/docs/mvc/controller/router.md", text: "beego 支持 RESTful 路由"})
+	idx.add(&searchDoc{kind: "docs", lang: "zh-CN", title: "模板", link: "/docs/mvc/view/tutorial.md", text: "模板语法"})
+	idx.add(&searchDoc{kind: "docs", lang: "zh-CN", title: "走", link: "/docs/go.md", text: "走"})
+
+	tests := []struct {
+		query string
+		links []string
+	}{
+		{"路", []string{"/docs/mvc/controller/router.md"}},
+		{"由", []string{"/docs/mvc/controller/router.md"}},
+		{"路由", []string{"/docs/mvc/controller/router.md"}},
+		{"路由设置", []string{"/docs/mvc/controller/router.md"}},
+		{"模", []string{"/docs/mvc/view/tutorial.md"}},
+		{"走", []string{"/docs/go.md"}},
+		{"restful", []string{"/docs/mvc/controller/router.md"}},
+		{"由设", []string{"/docs/mvc/controller/router.md"}},
+		{"设路", nil},
+		{"飞", nil},
+	}
+	for _, test := range tests {
+		results := idx.search(test.query, "zh-CN")
+		if len(results) != len(test.links) {
+			t.Errorf("search %q: got %d results, want %v", test.query, len(results), test.links)
+			continue
+		}
+		for i, r := range results {
+			if r.Link != test.links[i] {
+				t.Errorf("search %q: result %d is %s, want %s", test.query, i, r.Link, test.links[i])
+			}
+		}
+	}
+}
+
+func TestSearchLinks(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"docs/en-US/router.md": "---\nname: Router\n---\n\nbeego router\n",
+		"docs/ru-RU/router.md": "---\nname: Роутер\n---\n\nbeego router\n",
+		"blog/en-US/hello.md":  "Hello\n\nbeego router post\n",
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	beego.AppConfig.Set("lang::types", "en-US|ru-RU")
+	s := new(ContentStore)
+	var err error
+	if s.docs, err = parseDocs(filepath.Join(dir, "docs")); err != nil {
+		t.Fatal(err)
+	}
+	if s.blogs, err = parseDocs(filepath.Join(dir, "blog")); err != nil {
+		t.Fatal(err)
+	}
+
+	links := make(map[string]string)
+	for _, r := range buildSearchIndex(s).search("router", "") {
+		links[r.Link] = r.Lang
+	}
+	want := map[string]string{
+		"/en-US/docs/router.md": "en-US",
+		"/ru-RU/docs/router.md": "ru-RU",
+		"/en-US/blog/hello":     "en-US",
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("links of results are %v, want %v", links, want)
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Use the Router of beego", []string{"router"}, "Use the <mark>Router</mark> of beego"},
+		{"a <b> router & more", []string{"router"}, "a &lt;b&gt; <mark>router</mark> &amp; more"},
+		{"Ёлка и ёжик", analysis.Terms("ru-RU", "ёлка"), "<mark>Ёлка</mark> и ёжик"},
+		{"Елка и ёжик", analysis.Terms("ru-RU", "ЕЖИК"), "Елка и <mark>ёжик</mark>"},
+		{"路由设置", []string{"路由"}, "<mark>路由</mark>设置"},
+	}
+	for _, test := range tests {
+		if got := highlightSnippet(test.text, test.terms); got != test.want {
+			t.Errorf("highlightSnippet(%q, %q) = %q, want %q", test.text, test.terms, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"encoding/xml"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+// maxSitemapURLs is the most URLs a sitemap may have by the protocol.
+const maxSitemapURLs = 50000
+
+type sitemapURLSet struct {
+	XMLName xml.Name      `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	XHTML   string        `xml:"xmlns:xhtml,attr"`
+	URLs    []*sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string           `xml:"loc"`
+	LastMod    string           `xml:"lastmod,omitempty"`
+	Alternates []sitemapAltLink `xml:"xhtml:link"`
+
+	lastMod time.Time
+}
+
+type sitemapAltLink struct {
+	Rel      string `xml:"rel,attr"`
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []*sitemapLink `xml:"sitemap"`
+}
+
+type sitemapLink struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapSize returns number of URLs a sitemap has at most, by "sitemap::size".
+func sitemapSize() int {
+	size := beego.AppConfig.DefaultInt("sitemap::size", maxSitemapURLs)
+	if size <= 0 || size > maxSitemapURLs {
+		size = maxSitemapURLs
+	}
+	return size
+}
+
+// sitemapLangs returns languages the site is served in.
+func sitemapLangs() []string {
+	return strings.Split(beego.AppConfig.String("lang::types"), "|")
+}
+
+// langURL returns absolute URL of given path under prefix of lang,
+// or the path without prefix when lang is empty.
+func langURL(lang, p string) string {
+	if len(lang) > 0 {
+		p = "/" + lang + p
+	}
+	return BaseURL() + (&url.URL{Path: p}).EscapedPath()
+}
+
+// alternates returns links to given path in every language of langs, and the
+// one without language, which redirects to language of visitor, as default.
+func alternates(p string, langs []string) []sitemapAltLink {
+	links := make([]sitemapAltLink, 0, len(langs)+1)
+	for _, lang := range langs {
+		links = append(links, sitemapAltLink{Rel: "alternate", Hreflang: lang, Href: langURL(lang, p)})
+	}
+	return append(links, sitemapAltLink{Rel: "alternate", Hreflang: "x-default", Href: langURL("", p)})
+}
+
+// sitemapURLs returns URLs of given language version: pages of paths,
+// translated documents and blog posts.
+func sitemapURLs(s *ContentStore, lang string, paths []string) []*sitemapURL {
+	langs := sitemapLangs()
+	seen := make(map[string]bool)
+
+	var urls []*sitemapURL
+	add := func(p string, t time.Time, langs []string) {
+		u := &sitemapURL{Loc: langURL(lang, p), lastMod: t}
+		if seen[u.Loc] {
+			return
+		}
+		seen[u.Loc] = true
+		if !t.IsZero() {
+			u.LastMod = t.UTC().Format(time.RFC3339)
+		}
+		u.Alternates = alternates(p, langs)
+		urls = append(urls, u)
+	}
+
+	for _, p := range paths {
+		add(p, time.Time{}, langs)
+	}
+
+	if root := s.docs[lang]; root != nil {
+		updated := treeUpdated(s.docTree)
+
+		links := make([]string, 0, len(root.links))
+		for link := range root.links {
+			links = append(links, link)
+		}
+		sort.Strings(links)
+
+		for _, link := range links {
+			node := root.links[link]
+			if !isTranslated(node) {
+				continue
+			}
+			add("/docs/"+link, lastUpdated(node, lang, updated), translatedLangs(langs, func(l string) bool {
+				if root := s.docs[l]; root != nil {
+					n, _ := root.GetNodeByLink(link)
+					return n != nil && isTranslated(n)
+				}
+				return false
+			}))
+		}
+	}
+
+	updated := treeUpdated(s.blogTree)
+	for _, p := range s.posts[lang] {
+		if !isTranslated(p.DocNode) {
+			continue
+		}
+		slug := p.Slug
+		add("/blog/"+slug, lastUpdated(p.DocNode, lang, updated), translatedLangs(langs, func(l string) bool {
+			for _, p := range s.posts[l] {
+				if p.Slug == slug {
+					return isTranslated(p.DocNode)
+				}
+			}
+			return false
+		}))
+	}
+	return urls
+}
+
+// isTranslated returns true if node is a published document of its own language.
+func isTranslated(node *DocNode) bool {
+	return node.HasContent() && !node.Draft && len(node.Fallback) == 0
+}
+
+func translatedLangs(langs []string, has func(string) bool) []string {
+	var list []string
+	for _, lang := range langs {
+		if has(lang) {
+			list = append(list, lang)
+		}
+	}
+	return list
+}
+
+// SitemapPages returns number of sitemaps of given language version,
+// each of them has "sitemap::size" URLs at most.
+func SitemapPages(lang string, paths []string) int {
+	n := len(sitemapURLs(CurrentStore(), lang, paths))
+	return (n + sitemapSize() - 1) / sitemapSize()
+}
+
+// Sitemap returns sitemap of the site. It lists URLs of every language
+// when they fit in one sitemap, or it is an index of sitemaps of languages.
+func Sitemap(paths []string) ([]byte, error) {
+	s := CurrentStore()
+	size := sitemapSize()
+
+	all := make(map[string][]*sitemapURL)
+	var n int
+	for _, lang := range sitemapLangs() {
+		all[lang] = sitemapURLs(s, lang, paths)
+		n += len(all[lang])
+	}
+
+	if n <= size {
+		set := &sitemapURLSet{XHTML: "http://www.w3.org/1999/xhtml"}
+		for _, lang := range sitemapLangs() {
+			set.URLs = append(set.URLs, all[lang]...)
+		}
+		return marshalFeed(set)
+	}
+
+	index := new(sitemapIndex)
+	for _, lang := range sitemapLangs() {
+		urls := all[lang]
+		for page := 1; (page-1)*size < len(urls); page++ {
+			loc := langURL(lang, "/sitemap.xml")
+			if page > 1 {
+				loc += "?page=" + strconv.Itoa(page)
+			}
+			index.Sitemaps = append(index.Sitemaps, &sitemapLink{
+				Loc:     loc,
+				LastMod: latestMod(pageOf(urls, page, size)),
+			})
+		}
+	}
+	return marshalFeed(index)
+}
+
+// LangSitemap returns given page, starting at 1, of sitemap of given language version.
+func LangSitemap(lang string, paths []string, page int) ([]byte, error) {
+	urls := sitemapURLs(CurrentStore(), lang, paths)
+	return marshalFeed(&sitemapURLSet{
+		XHTML: "http://www.w3.org/1999/xhtml",
+		URLs:  pageOf(urls, page, sitemapSize()),
+	})
+}
+
+func pageOf(urls []*sitemapURL, page, size int) []*sitemapURL {
+	start := (page - 1) * size
+	if start < 0 || start >= len(urls) {
+		return nil
+	}
+	end := start + size
+	if end > len(urls) {
+		end = len(urls)
+	}
+	return urls[start:end]
+}
+
+func latestMod(urls []*sitemapURL) string {
+	var latest time.Time
+	for _, u := range urls {
+		if u.lastMod.After(latest) {
+			latest = u.lastMod
+		}
+	}
+	if latest.IsZero() {
+		return ""
+	}
+	return latest.UTC().Format(time.RFC3339)
+}
+
+// Robots returns content of robots.txt: paths of "robots::disallow"
+// are disallowed to all crawlers, and the sitemap is announced.
+func Robots() string {
+	var buf strings.Builder
+	buf.WriteString("User-agent: *\n")
+
+	disallow := beego.AppConfig.String("robots::disallow")
+	if len(disallow) == 0 {
+		buf.WriteString("Disallow:\n")
+	}
+	for _, p := range strings.Split(disallow, "|") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			buf.WriteString("Disallow: " + p + "\n")
+		}
+	}
+	if delay := beego.AppConfig.DefaultInt("robots::crawl_delay", 0); delay > 0 {
+		buf.WriteString("Crawl-delay: " + strconv.Itoa(delay) + "\n")
+	}
+
+	buf.WriteString("\nSitemap: " + BaseURL() + "/sitemap.xml\n")
+	return buf.String()
+}
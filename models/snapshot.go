@@ -0,0 +1,362 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/utils"
+)
+
+// Every sync writes into a new snapshot directory,
+// CurrentSnapshotFile names the snapshot being served.
+const (
+	snapshotsDir        = "content/snapshots"
+	CurrentSnapshotFile = "content/current"
+)
+
+func snapshotPath(version string) string {
+	return path.Join(snapshotsDir, version)
+}
+
+// ContentPath returns path of file in active snapshot by given path relative to snapshot root.
+func ContentPath(p string) string {
+	return path.Join(snapshotsDir, CurrentStore().version, p)
+}
+
+// Snapshots returns versions of all snapshots from oldest to newest.
+func Snapshots() ([]string, error) {
+	fis, err := ioutil.ReadDir(snapshotsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		if fi.IsDir() && !strings.HasPrefix(fi.Name(), ".") {
+			versions = append(versions, fi.Name())
+		}
+	}
+	// Versions are timestamps.
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// CurrentSnapshot returns version of snapshot named by CurrentSnapshotFile,
+// which is the one served after the server picks up the change.
+func CurrentSnapshot() string {
+	data, err := ioutil.ReadFile(CurrentSnapshotFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// writePointer replaces CurrentSnapshotFile atomically.
+func writePointer(version string) error {
+	tmp := CurrentSnapshotFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(version+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, CurrentSnapshotFile)
+}
+
+// newSnapshot creates a new snapshot directory as a copy of the active one.
+// Files are hard linked, so writers must replace files instead of modifying them.
+func newSnapshot() (string, error) {
+	if err := os.MkdirAll(snapshotsDir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	var version, dir string
+	for {
+		version = time.Now().Format("20060102-150405.000")
+		dir = snapshotPath(version)
+		err := os.Mkdir(dir, os.ModePerm)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		// Versions must be unique.
+		time.Sleep(time.Millisecond)
+	}
+
+	if cur := CurrentStore().version; len(cur) > 0 {
+		if err := linkTree(snapshotPath(cur), dir); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return version, nil
+}
+
+// linkTree copies directory src to dst with hard links,
+// files are copied when hard links are not supported.
+func linkTree(src, dst string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		if err = os.Link(p, target); err != nil {
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(target, data, info.Mode())
+		}
+		return nil
+	})
+}
+
+// loadSnapshot loads and validates content of snapshot by given version.
+// It does not change content being served.
+func loadSnapshot(version string) (*ContentStore, error) {
+	dir := snapshotPath(version)
+	if len(version) == 0 || !utils.FileExists(dir) {
+		return nil, errors.New("snapshot does not exist")
+	}
+
+	snap := &ContentStore{version: version}
+	var err error
+	if snap.docs, err = parseDocs(path.Join(dir, "docs")); err != nil {
+		return nil, errors.New("parse docs: " + err.Error())
+	}
+	if snap.blogs, err = parseDocs(path.Join(dir, "blog")); err != nil {
+		return nil, errors.New("parse blog: " + err.Error())
+	}
+	mergeFallbacks(snap.docs, path.Join(dir, "docs"))
+	mergeFallbacks(snap.blogs, path.Join(dir, "blog"))
+	snap.posts = buildPosts(snap.blogs)
+	snap.docTree = loadTreeFile(path.Join(dir, "docTree.json"))
+	snap.blogTree = loadTreeFile(path.Join(dir, "blogTree.json"))
+	if snap.products, err = loadProducts(dir, &snap.productTree); err != nil {
+		// Products are optional.
+		beego.Error(err)
+		snap.products = new(products)
+	}
+	snap.search = buildSearchIndex(snap)
+	if snap.redirects, err = loadRedirects(dir); err != nil {
+		// Redirects are optional.
+		beego.Error(err)
+	}
+	return snap, nil
+}
+
+// activateSnapshot replaces content being served by given snapshot
+// and makes it the one to load on next start.
+func activateSnapshot(snap *ContentStore) error {
+	if err := writePointer(snap.version); err != nil {
+		return err
+	}
+	useSnapshot(snap)
+	return nil
+}
+
+func useSnapshot(snap *ContentStore) {
+	setStore(snap)
+
+	// Documents of other snapshots will not be requested any more.
+	if dir, err := filepath.Abs(snapshotPath(snap.version)); err == nil {
+		getRenderCache().retain(dir + string(filepath.Separator))
+	}
+	beego.Info("Content snapshot", snap.version, "loaded")
+}
+
+// pruneSnapshots removes oldest snapshots but the number set by "snapshots::keep",
+// the active one is always kept.
+func pruneSnapshots() {
+	keep := beego.AppConfig.DefaultInt("snapshots::keep", 5)
+	if keep < 1 {
+		keep = 1
+	}
+
+	versions, err := Snapshots()
+	if err != nil {
+		beego.Error("models.pruneSnapshots ->", err)
+		return
+	}
+
+	active := CurrentStore().version
+	for i := 0; i < len(versions)-keep; i++ {
+		if versions[i] == active {
+			continue
+		}
+		beego.Info("Remove snapshot:", versions[i])
+		if err = os.RemoveAll(snapshotPath(versions[i])); err != nil {
+			beego.Error("models.pruneSnapshots ->", err)
+		}
+	}
+}
+
+// initSnapshots loads snapshot named by CurrentSnapshotFile, or the newest one that
+// loads without errors if it is broken. Content of old layout is moved into
+// the first snapshot.
+func initSnapshots() error {
+	if !utils.FileExists(CurrentSnapshotFile) {
+		if err := migrateLegacyContent(); err != nil {
+			return errors.New("models.initSnapshots -> migrate content: " + err.Error())
+		}
+	}
+
+	// Redirects of older versions belong to the snapshot they were made for.
+	if cur := CurrentSnapshot(); len(cur) > 0 && utils.FileExists(legacyRedirectsFile) &&
+		!utils.FileExists(path.Join(snapshotPath(cur), redirectsFile)) {
+		beego.Info("Move", legacyRedirectsFile, "into content snapshot", cur)
+		if err := os.Rename(legacyRedirectsFile, path.Join(snapshotPath(cur), redirectsFile)); err != nil {
+			return errors.New("models.initSnapshots -> migrate redirects: " + err.Error())
+		}
+	}
+
+	versions, err := Snapshots()
+	if err != nil {
+		return errors.New("models.initSnapshots -> list snapshots: " + err.Error())
+	}
+	if len(versions) == 0 {
+		beego.Info("No content snapshot yet")
+		return nil
+	}
+
+	// Try the current one first, then from newest to oldest.
+	cur := CurrentSnapshot()
+	var candidates []string
+	if len(cur) > 0 {
+		candidates = append(candidates, cur)
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i] != cur {
+			candidates = append(candidates, versions[i])
+		}
+	}
+
+	for _, version := range candidates {
+		snap, err := loadSnapshot(version)
+		if err != nil {
+			beego.Error("models.initSnapshots -> load snapshot "+version+":", err)
+			continue
+		}
+		if version != cur {
+			beego.Warn("Fall back to content snapshot", version)
+			return activateSnapshot(snap)
+		}
+		useSnapshot(snap)
+		return nil
+	}
+	return errors.New("models.initSnapshots -> no snapshot can be loaded")
+}
+
+// migrateLegacyContent moves content directories and tree files
+// from places used before snapshots into the first snapshot.
+func migrateLegacyContent() error {
+	moves := map[string]string{
+		"docs":                  "docs",
+		"blog":                  "blog",
+		"products":              "products",
+		"conf/docTree.json":     "docTree.json",
+		"conf/blogTree.json":    "blogTree.json",
+		"conf/productTree.json": "productTree.json",
+		legacyRedirectsFile:     redirectsFile,
+	}
+
+	found := false
+	for from := range moves {
+		if utils.FileExists(from) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	version, err := newSnapshot()
+	if err != nil {
+		return err
+	}
+	for from, to := range moves {
+		if !utils.FileExists(from) {
+			continue
+		}
+		beego.Info("Move", from, "into content snapshot", version)
+		if err = os.Rename(from, path.Join(snapshotPath(version), to)); err != nil {
+			return err
+		}
+	}
+	return writePointer(version)
+}
+
+// Rollback points CurrentSnapshotFile to snapshot of given version after it
+// has been validated, an empty version means the one before current snapshot.
+// A running server loads it when it sees the CurrentSnapshotFile change.
+func Rollback(version string) error {
+	if len(version) == 0 {
+		versions, err := Snapshots()
+		if err != nil {
+			return err
+		}
+		active := CurrentSnapshot()
+		for i := len(versions) - 1; i > 0; i-- {
+			if versions[i] == active {
+				version = versions[i-1]
+				break
+			}
+		}
+		if len(version) == 0 {
+			return errors.New("no snapshot before " + active)
+		}
+	}
+
+	if _, err := loadSnapshot(version); err != nil {
+		return errors.New("load snapshot " + version + ": " + err.Error())
+	}
+	return writePointer(version)
+}
+
+// ReloadSnapshot loads snapshot named by CurrentSnapshotFile when it is not the one being served,
+// e.g. after "beeweb rollback".
+func ReloadSnapshot() error {
+	syncLock.Lock()
+	defer syncLock.Unlock()
+
+	version := CurrentSnapshot()
+	if len(version) == 0 || version == CurrentStore().version {
+		return nil
+	}
+
+	snap, err := loadSnapshot(version)
+	if err != nil {
+		return errors.New("models.ReloadSnapshot -> load snapshot " + version + ": " + err.Error())
+	}
+	useSnapshot(snap)
+	return nil
+}
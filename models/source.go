@@ -0,0 +1,315 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/astaxie/beego"
+)
+
+// TreeEntry describes a file of a content source and the hash of its content.
+type TreeEntry struct {
+	Path string
+	Sha  string
+	Type string
+}
+
+// ContentSource is a place where documentation, blog or products files are mirrored from.
+type ContentSource interface {
+	// Tree returns all files of the source with their hashes.
+	Tree(ctx context.Context) ([]*TreeEntry, error)
+	// Fetch returns content of file by given path in the tree.
+	Fetch(ctx context.Context, path string) ([]byte, error)
+}
+
+// contentSection binds a content source to the directory it is mirrored to
+// and the file saving its tree of last sync, both relative to a snapshot.
+type contentSection struct {
+	Name     string
+	TreeName string
+	Prefix   string
+	URL      string // URL of upstream repository, pushes to it are synced.
+	Branch   string
+	Source   ContentSource
+
+	// failed is true when some files failed to fetch in last sync.
+	failed bool
+}
+
+var sections []*contentSection
+
+// defaultRepos are upstream repositories of sections when app.conf does not specify one.
+var defaultRepos = map[string]string{
+	"docs":     "beego/beedoc",
+	"blog":     "beego/beeblog",
+	"products": "beego/products",
+}
+
+func initSections() {
+	sections = sections[:0]
+	for _, sec := range []*contentSection{
+		{Name: "docs", TreeName: "docTree.json", Prefix: "docs/"},
+		{Name: "blog", TreeName: "blogTree.json", Prefix: "blog/"},
+		{Name: "products", TreeName: "productTree.json", Prefix: "products/"},
+	} {
+		src, err := newContentSource(sec.Name)
+		if err != nil {
+			beego.Error("models.initSections -> "+sec.Name+":", err)
+			continue
+		}
+
+		sec.Source = src
+		sec.URL = repoURL(sec.Name)
+		sec.Branch = beego.AppConfig.DefaultString(sec.Name+"::branch", "master")
+		sections = append(sections, sec)
+	}
+}
+
+// repoURL returns URL of upstream repository of given section by app.conf,
+// or an empty string for local sources and git ones without url.
+func repoURL(section string) string {
+	conf := func(key, def string) string {
+		return beego.AppConfig.DefaultString(section+"::"+key, def)
+	}
+
+	repo := conf("repo", defaultRepos[section])
+	switch conf("source", "github") {
+	case "github":
+		return "https://github.com/" + repo
+	case "gitea", "gitlab":
+		if baseURL := strings.TrimSuffix(conf("url", ""), "/"); len(baseURL) > 0 {
+			return baseURL + "/" + repo
+		}
+	case "git":
+		return conf("url", "")
+	}
+	return ""
+}
+
+// newContentSource creates content source by configuration of given section in app.conf.
+func newContentSource(section string) (ContentSource, error) {
+	conf := func(key, def string) string {
+		return beego.AppConfig.DefaultString(section+"::"+key, def)
+	}
+
+	repo := conf("repo", defaultRepos[section])
+	branch := conf("branch", "master")
+
+	switch kind := conf("source", "github"); kind {
+	case "github":
+		return &githubSource{repo: repo, branch: branch}, nil
+	case "gitea", "gitlab":
+		baseURL := strings.TrimSuffix(conf("url", ""), "/")
+		if len(baseURL) == 0 {
+			return nil, errors.New(kind + " source requires url")
+		}
+		if kind == "gitea" {
+			return &giteaSource{baseURL: baseURL, repo: repo, branch: branch, token: conf("token", "")}, nil
+		}
+		return &gitlabSource{baseURL: baseURL, repo: repo, branch: branch, token: conf("token", "")}, nil
+	case "local":
+		dir := conf("path", "")
+		if len(dir) == 0 {
+			return nil, errors.New("local source requires path")
+		}
+		return &localSource{dir: dir}, nil
+	case "git":
+		dir := conf("path", "")
+		if len(dir) == 0 {
+			return nil, errors.New("git source requires path")
+		}
+		depth, _ := strconv.Atoi(conf("depth", "1"))
+		return &gitSource{url: conf("url", ""), dir: dir, branch: branch, depth: depth}, nil
+	default:
+		return nil, errors.New("unknown source type " + kind)
+	}
+}
+
+// githubSource reads files through GitHub trees API and raw file host.
+type githubSource struct {
+	repo, branch string
+}
+
+func (s *githubSource) Tree(ctx context.Context) ([]*TreeEntry, error) {
+	var tree struct {
+		Tree []*TreeEntry
+	}
+	_, err := getHttpJson(ctx, "https://api.github.com/repos/"+s.repo+"/git/trees/"+
+		s.branch+"?recursive=1&"+githubCred, nil, &tree)
+	return tree.Tree, err
+}
+
+func (s *githubSource) Fetch(ctx context.Context, path string) ([]byte, error) {
+	return getHttpRaw(ctx, "https://raw.githubusercontent.com/"+s.repo+"/"+s.branch+"/"+escapePath(path), nil)
+}
+
+// escapePath escapes every segment of file path p for use in URL path.
+func escapePath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+// giteaSource reads files through Gitea API.
+type giteaSource struct {
+	baseURL, repo, branch, token string
+}
+
+func (s *giteaSource) header() http.Header {
+	header := make(http.Header)
+	if len(s.token) > 0 {
+		header.Set("Authorization", "token "+s.token)
+	}
+	return header
+}
+
+func (s *giteaSource) Tree(ctx context.Context) ([]*TreeEntry, error) {
+	var entries []*TreeEntry
+	for page := 1; ; page++ {
+		var tree struct {
+			Tree      []*TreeEntry
+			Truncated bool
+		}
+		_, err := getHttpJson(ctx, fmt.Sprintf("%s/api/v1/repos/%s/git/trees/%s?recursive=true&page=%d",
+			s.baseURL, s.repo, url.PathEscape(s.branch), page), s.header(), &tree)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, tree.Tree...)
+		if !tree.Truncated || len(tree.Tree) == 0 {
+			return entries, nil
+		}
+		if page == 1 {
+			uncacheable(ctx)
+		}
+	}
+}
+
+func (s *giteaSource) Fetch(ctx context.Context, path string) ([]byte, error) {
+	return getHttpRaw(ctx, s.baseURL+"/api/v1/repos/"+s.repo+"/raw/"+escapePath(path)+
+		"?ref="+url.QueryEscape(s.branch), s.header())
+}
+
+// gitlabSource reads files through GitLab API v4.
+type gitlabSource struct {
+	baseURL, repo, branch, token string
+}
+
+func (s *gitlabSource) header() http.Header {
+	header := make(http.Header)
+	if len(s.token) > 0 {
+		header.Set("PRIVATE-TOKEN", s.token)
+	}
+	return header
+}
+
+func (s *gitlabSource) project() string {
+	return s.baseURL + "/api/v4/projects/" + url.PathEscape(s.repo)
+}
+
+func (s *gitlabSource) Tree(ctx context.Context) ([]*TreeEntry, error) {
+	var entries []*TreeEntry
+	for page := "1"; len(page) > 0; {
+		var tree []struct {
+			Id   string
+			Path string
+			Type string
+		}
+		header, err := getHttpJson(ctx, s.project()+"/repository/tree?recursive=true&per_page=100&ref="+
+			url.QueryEscape(s.branch)+"&page="+page, s.header(), &tree)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range tree {
+			entries = append(entries, &TreeEntry{Path: t.Path, Sha: t.Id, Type: t.Type})
+		}
+		if page == "1" && len(header.Get("X-Next-Page")) > 0 {
+			uncacheable(ctx)
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return entries, nil
+}
+
+func (s *gitlabSource) Fetch(ctx context.Context, path string) ([]byte, error) {
+	return getHttpRaw(ctx, s.project()+"/repository/files/"+url.PathEscape(path)+
+		"/raw?ref="+url.QueryEscape(s.branch), s.header())
+}
+
+// localSource reads files from a plain directory.
+// Hashes are computed the same way as git does for blobs.
+type localSource struct {
+	dir string
+}
+
+func (s *localSource) Tree(ctx context.Context) ([]*TreeEntry, error) {
+	var entries []*TreeEntry
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, &TreeEntry{
+			Path: filepath.ToSlash(rel),
+			Sha:  blobHash(data),
+			Type: "blob",
+		})
+		return nil
+	})
+	return entries, err
+}
+
+func (s *localSource) Fetch(ctx context.Context, path string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(s.dir, filepath.FromSlash(path)))
+}
+
+// blobHash returns git object hash of a blob with given content.
+func blobHash(data []byte) string {
+	h := sha1.New()
+	h.Write([]byte("blob " + strconv.Itoa(len(data)) + "\x00"))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,109 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ContentStore holds documentation, blog posts, product cases and search index
+// loaded from a content snapshot, together with trees of the snapshot.
+// It is never modified once built: a reload builds a new store and swaps
+// it in, so a request keeps a consistent view by holding the store it got.
+type ContentStore struct {
+	version     string
+	docs        map[string]*DocRoot
+	blogs       map[string]*DocRoot
+	posts       map[string][]*Post
+	docTree     []oldDocNode
+	blogTree    []oldDocNode
+	productTree []oldDocNode
+	products    *products
+	search      *searchIndex
+	redirects   map[string]map[string]string // Old URL to new URL by language.
+}
+
+var (
+	store      atomic.Value // *ContentStore
+	emptyStore = &ContentStore{
+		docs:     make(map[string]*DocRoot),
+		blogs:    make(map[string]*DocRoot),
+		products: new(products),
+		search:   newSearchIndex(),
+	}
+)
+
+// CurrentStore returns content store being served.
+func CurrentStore() *ContentStore {
+	if s, ok := store.Load().(*ContentStore); ok {
+		return s
+	}
+	return emptyStore
+}
+
+func setStore(s *ContentStore) {
+	store.Store(s)
+}
+
+// Version returns version of the snapshot store is loaded from.
+func (s *ContentStore) Version() string {
+	return s.version
+}
+
+// DocRoot returns documentation of given language.
+func (s *ContentStore) DocRoot(lang string) *DocRoot {
+	return s.docs[lang]
+}
+
+// Page returns document of given section, "docs" or "blog", by name and language version.
+func (s *ContentStore) Page(section, name, lang string) *DocNode {
+	var root *DocRoot
+	switch section {
+	case "docs":
+		root = s.docs[lang]
+	case "blog":
+		root = s.blogs[lang]
+	}
+	if root == nil {
+		return nil
+	}
+
+	node, _ := root.GetPage(name)
+	return node
+}
+
+// Updated returns when document of given section, "docs" or "blog", changed last,
+// by time a sync saw its file change or date of its front matter.
+func (s *ContentStore) Updated(section string, node *DocNode) time.Time {
+	return lastUpdated(node, node.Root.Lang, treeUpdated(s.tree(section+"/")))
+}
+
+// Products returns product cases.
+func (s *ContentStore) Products() *products {
+	return s.products
+}
+
+// tree returns the tree of last sync of section by given prefix.
+func (s *ContentStore) tree(prefix string) []oldDocNode {
+	switch prefix {
+	case "docs/":
+		return s.docTree
+	case "blog/":
+		return s.blogTree
+	default:
+		return s.productTree
+	}
+}
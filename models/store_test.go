@@ -0,0 +1,153 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/astaxie/beego"
+)
+
+// writeSnapshot writes a snapshot of given version whose documents,
+// blog posts and product cases are all named by version.
+func writeSnapshot(t *testing.T, version string) {
+	files := map[string]string{
+		"docs/en-US/intro.md":    "---\nname: Intro " + version + "\n---\n\nWelcome to beego " + version + ".\n",
+		"blog/en-US/hello.md":    "Hello " + version + "\n\nFirst post of " + version + ".\n",
+		"docTree.json":           `{"Tree":[{"Sha":"` + version + `","Path":"en-US/intro","Type":"blob"}]}`,
+		"blogTree.json":          `{"Tree":[{"Sha":"` + version + `","Path":"en-US/hello","Type":"blob"}]}`,
+		"productTree.json":       `{"Tree":[{"Sha":"` + version + `","Path":"projects","Type":"blob"}]}`,
+		"products/projects.json": `{"Projects":[{"Name":"` + version + `"}]}`,
+		"redirects.json":         `{"en-US":{"/docs/old":"/docs/` + version + `"}}`,
+	}
+	for name, content := range files {
+		name = path.Join(snapshotPath(version), name)
+		if err := os.MkdirAll(path.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestStoreReload serves requests while snapshots are reloaded, every
+// request must see content of a single snapshot. Run it with -race.
+func TestStoreReload(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer setStore(emptyStore)
+
+	beego.AppConfig.Set("lang::types", "en-US")
+	versions := []string{"v1", "v2"}
+	for _, v := range versions {
+		writeSnapshot(t, v)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				checkStore(t, CurrentStore())
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		v := versions[i%len(versions)]
+		snap, err := loadSnapshot(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = activateSnapshot(snap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if v := CurrentSnapshot(); v != "v2" {
+		t.Errorf("current snapshot is %q, want v2", v)
+	}
+}
+
+// checkStore reads everything requests read from s and checks that all of it
+// comes from the snapshot of s.
+func checkStore(t *testing.T, s *ContentStore) {
+	v := s.Version()
+	if len(v) == 0 {
+		return
+	}
+
+	root := s.DocRoot("en-US")
+	if root == nil {
+		t.Errorf("%s: no documentation", v)
+		return
+	}
+	doc, ok := root.GetNodeByLink("intro.md")
+	if !ok || doc.Name != "Intro "+v {
+		t.Errorf("%s: document is %v", v, doc)
+		return
+	}
+	doc.Render()
+
+	posts := s.posts["en-US"]
+	if len(posts) != 1 || posts[0].Name != "Hello "+v || posts[0].Render() == nil {
+		t.Errorf("%s: posts are %v", v, posts)
+	}
+	if page := s.Page("blog", "hello", "en-US"); page == nil || page.Name != "Hello "+v {
+		t.Errorf("%s: blog page is %v", v, page)
+	}
+	if p := s.Products().Projects; len(p) != 1 || p[0].Name != v {
+		t.Errorf("%s: products are %v", v, p)
+	}
+	for _, prefix := range []string{"docs/", "blog/", "products/"} {
+		if tree := s.tree(prefix); len(tree) != 1 || tree[0].Sha != v {
+			t.Errorf("%s: tree of %s is %v", v, prefix, tree)
+		}
+	}
+	if to := s.redirects["en-US"]["/docs/old"]; to != "/docs/"+v {
+		t.Errorf("%s: redirect to %s", v, to)
+	}
+	if results := s.search.search("beego", "en-US"); len(results) != 1 {
+		t.Errorf("%s: %d search results", v, len(results))
+	}
+
+	// Package functions read the store being served, which may be another one.
+	GetPosts("en-US")
+	GetPost("hello", "en-US")
+	GetPostsByTag("go", "en-US")
+	GetProducts()
+	GetRedirect("en-US", "/docs/old")
+	Search("beego", "en-US", 10, 0)
+}
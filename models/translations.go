@@ -0,0 +1,152 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+)
+
+// Status of a translated document.
+const (
+	TranslationOK      = "ok"
+	TranslationMissing = "missing"
+	TranslationStale   = "stale"
+)
+
+// Translation is state of a document in a language.
+type Translation struct {
+	Lang    string
+	Status  string
+	Name    string
+	Updated time.Time
+}
+
+// TranslationPage is a document of the source language with its translations.
+type TranslationPage struct {
+	Link         string
+	Name         string
+	Updated      time.Time
+	Translations []*Translation
+}
+
+// TranslationReport compares documentation of every language with DefaultLang.
+type TranslationReport struct {
+	Source  string
+	Langs   []string
+	Pages   []*TranslationPage
+	Missing map[string]int
+	Stale   map[string]int
+}
+
+// Translations returns translation status of documentation being served.
+func Translations() *TranslationReport {
+	return translationReport(CurrentStore())
+}
+
+// LoadTranslations returns translation status of documentation of the
+// current snapshot, without starting to serve it.
+func LoadTranslations() (*TranslationReport, error) {
+	snap, err := loadSnapshot(CurrentSnapshot())
+	if err != nil {
+		return nil, errors.New("models.LoadTranslations -> " + err.Error())
+	}
+	return translationReport(snap), nil
+}
+
+func translationReport(s *ContentStore) *TranslationReport {
+	report := &TranslationReport{
+		Source:  DefaultLang,
+		Missing: make(map[string]int),
+		Stale:   make(map[string]int),
+	}
+	for _, lang := range strings.Split(beego.AppConfig.String("lang::types"), "|") {
+		if lang != DefaultLang {
+			report.Langs = append(report.Langs, lang)
+		}
+	}
+
+	src := s.docs[DefaultLang]
+	if src == nil {
+		return report
+	}
+
+	updated := make(map[string]time.Time, len(s.docTree))
+	for _, node := range s.docTree {
+		updated[node.Path] = node.Updated
+	}
+
+	for link, node := range src.links {
+		if !node.HasContent() || len(node.Fallback) > 0 {
+			continue
+		}
+
+		page := &TranslationPage{
+			Link:    link,
+			Name:    node.Name,
+			Updated: lastUpdated(node, DefaultLang, updated),
+		}
+		for _, lang := range report.Langs {
+			t := &Translation{Lang: lang, Status: TranslationMissing}
+
+			var n *DocNode
+			if root := s.docs[lang]; root != nil {
+				n, _ = root.GetNodeByLink(link)
+			}
+			if n != nil && n.HasContent() && len(n.Fallback) == 0 {
+				t.Name = n.Name
+				t.Updated = lastUpdated(n, lang, updated)
+				if page.Updated.After(t.Updated) {
+					t.Status = TranslationStale
+				} else {
+					t.Status = TranslationOK
+				}
+			}
+
+			switch t.Status {
+			case TranslationMissing:
+				report.Missing[lang]++
+			case TranslationStale:
+				report.Stale[lang]++
+			}
+			page.Translations = append(page.Translations, t)
+		}
+		report.Pages = append(report.Pages, page)
+	}
+
+	sort.Slice(report.Pages, func(i, j int) bool {
+		return report.Pages[i].Link < report.Pages[j].Link
+	})
+	return report
+}
+
+// lastUpdated returns when document changed last, by time its file changed
+// upstream in tree of last sync or date of its front matter, whichever is later.
+func lastUpdated(node *DocNode, lang string, updated map[string]time.Time) time.Time {
+	rel := node.RelPath
+	if node.IsDir {
+		rel = node.FileRelPath
+	}
+
+	t := updated[lang+"/"+strings.TrimSuffix(rel, ".md")]
+	if node.Date.After(t) {
+		t = node.Date
+	}
+	return t
+}
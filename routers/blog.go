@@ -15,12 +15,16 @@
 package routers
 
 import (
+	"strconv"
 	"strings"
 
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/utils/pagination"
+
 	"github.com/beego/beeweb/models"
 )
 
-// BlogRouter serves about page.
+// BlogRouter serves blog posts and their listings.
 type BlogRouter struct {
 	baseRouter
 }
@@ -30,19 +34,69 @@ func (this *BlogRouter) Get() {
 	this.Data["IsBlog"] = true
 	this.TplName = "blog.html"
 
-	reqUrl := this.Ctx.Request.URL.String()
-	fullName := reqUrl[strings.LastIndex(reqUrl, "/")+1:]
-	if qm := strings.Index(fullName, "?"); qm > -1 {
-		fullName = fullName[:qm]
+	slug := strings.Trim(this.GetString(":splat"), "/")
+	if len(slug) == 0 {
+		this.Index()
+		return
 	}
 
-	df := models.GetBlog(fullName, this.Lang)
-	if df == nil {
-		this.Redirect("/blog", 302)
+	post := models.GetPost(slug, this.Lang)
+	if post == nil {
+		if to, ok := models.GetRedirect(this.Lang, "/blog/"+slug); ok {
+			this.Redirect(this.langURL(to), 301)
+			return
+		}
+		this.Abort("404")
 		return
 	}
 
-	this.Data["Title"] = df.Title
-	this.Data["Data"] = string(df.Data)
+	this.Data["Title"] = post.Name
+	this.Data["Post"] = post
+	this.setDocSEO(post.DocNode, "blog", "/blog/"+post.Slug,
+		this.crumb(this.Tr("homepage"), "/"), this.crumb(this.Tr("blog"), "/blog"))
+	this.Data["Data"] = post.GetContent()
+	this.Data["Fallback"] = post.Fallback
 	this.Data["IsHasMarkdown"] = true
 }
+
+// Index serves all blog posts, newest first.
+func (this *BlogRouter) Index() {
+	this.Data["Title"] = this.Tr("blog")
+	this.listPosts(models.GetPosts(this.Lang))
+}
+
+// Tag serves blog posts tagged by the tag in URL.
+func (this *BlogRouter) Tag() {
+	tag := this.GetString(":tag")
+	this.Data["Title"] = this.Tr("posts_tagged", tag)
+	this.listPosts(models.GetPostsByTag(tag, this.Lang))
+}
+
+// Author serves blog posts written by the author in URL.
+func (this *BlogRouter) Author() {
+	name := this.GetString(":name")
+	this.Data["Title"] = this.Tr("posts_by", name)
+	this.listPosts(models.GetPostsByAuthor(name, this.Lang))
+}
+
+// listPosts renders page of posts chosen by query argument "p".
+func (this *BlogRouter) listPosts(posts []*models.Post) {
+	this.Data["IsBlog"] = true
+	this.TplName = "blog_index.html"
+
+	p := pagination.SetPaginator(this.Ctx, beego.AppConfig.DefaultInt("blog::per_page", 10), int64(len(posts)))
+	end := p.Offset() + p.PerPageNums
+	if end > len(posts) {
+		end = len(posts)
+	}
+	if p.Offset() < end {
+		this.Data["Posts"] = posts[p.Offset():end]
+	}
+
+	if title, ok := this.Data["Title"].(string); ok {
+		this.seo().Title = title
+	}
+	if p.Page() > 1 {
+		this.seo().Canonical += "?p=" + strconv.Itoa(p.Page())
+	}
+}
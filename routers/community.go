@@ -14,10 +14,6 @@
 
 package routers
 
-import (
-	"github.com/beego/beeweb/models"
-)
-
 // CommunityRouter serves community page.
 type CommunityRouter struct {
 	baseRouter
@@ -28,7 +24,5 @@ func (this *CommunityRouter) Get() {
 	this.Data["IsCommunity"] = true
 	this.TplName = "community.html"
 
-	df := models.GetDoc("usecases", this.Lang)
-	this.Data["Section"] = "usecases"
-	this.Data["Data"] = string(df.Data)
+	this.setPage("usecases")
 }
@@ -17,9 +17,11 @@ package routers
 import (
 	"io"
 	"os"
+	"path"
 
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/context"
+	"github.com/astaxie/beego/utils"
 	"github.com/beego/i18n"
 
 	"github.com/beego/beeweb/models"
@@ -49,7 +51,7 @@ func (this *DocsRouter) Get() {
 		if dRoot.Doc.HasContent() {
 			doc = dRoot.Doc
 		} else {
-			this.Redirect("/docs/intro/", 302)
+			this.Redirect(this.langURL("/docs/intro/"), 302)
 			return
 		}
 	} else {
@@ -60,6 +62,14 @@ func (this *DocsRouter) Get() {
 	}
 
 	if doc == nil {
+		if alias, ok := dRoot.GetNodeByAlias(link); ok {
+			this.Redirect(this.langURL("/docs/"+alias.Link), 301)
+			return
+		}
+		if to, ok := models.GetRedirect(this.Lang, "/docs/"+link); ok {
+			this.Redirect(this.langURL(to), 301)
+			return
+		}
 		this.Abort("404")
 		return
 	}
@@ -67,27 +77,72 @@ func (this *DocsRouter) Get() {
 	this.Data["DocRoot"] = dRoot
 	this.Data["Doc"] = doc
 	this.Data["Title"] = doc.Name
-	this.Data["Data"] = doc.GetContent()
+	this.setDocSEO(doc, "docs", "/docs/"+doc.Link, this.docCrumbs(dRoot, doc)...)
+
+	r := doc.Render()
+	this.Data["Data"] = string(r.HTML)
+	this.Data["TOC"] = r.Headings
+}
+
+// docCrumbs returns breadcrumbs of directories leading to doc, without doc.
+func (this *DocsRouter) docCrumbs(dRoot *models.DocRoot, doc *models.DocNode) []*Breadcrumb {
+	crumbs := []*Breadcrumb{this.crumb(this.Tr("homepage"), "/")}
+	if doc == dRoot.Doc {
+		return crumbs
+	}
+	crumbs = append(crumbs, this.crumb(this.Tr("docs"), "/docs/"))
+
+	var dirs []*Breadcrumb
+	for p := doc.Parent; p != nil && p != dRoot.Doc; p = p.Parent {
+		if p.HasContent() && len(p.Name) > 0 {
+			dirs = append([]*Breadcrumb{this.crumb(p.Name, "/docs/"+p.Link)}, dirs...)
+		}
+	}
+	return append(crumbs, dirs...)
 }
 
 func DocsStatic(ctx *context.Context) {
 	if uri := ctx.Input.Param(":all"); len(uri) > 0 {
-		lang := ctx.GetCookie("lang")
+		lang := ctx.Input.Param(":lang")
 		if !i18n.IsExist(lang) {
-			lang = "en-US"
+			lang = ctx.GetCookie("lang")
 		}
-
-		f, err := os.Open("docs/" + lang + "/" + "images/" + uri)
-		if err != nil {
-			ctx.WriteString(err.Error())
-			return
+		if !i18n.IsExist(lang) {
+			lang = models.DefaultLang
 		}
-		defer f.Close()
 
-		_, err = io.Copy(ctx.ResponseWriter, f)
-		if err != nil {
-			ctx.WriteString(err.Error())
-			return
+		// Untranslated documents show images of the language they are taken from.
+		for _, l := range append([]string{lang}, models.FallbackLangs(lang)...) {
+			dir := "docs/" + l + "/images"
+			if utils.FileExists(models.ContentPath(path.Join(dir, path.Clean("/"+uri)))) {
+				serveContentFile(ctx, dir, uri)
+				return
+			}
 		}
+		serveContentFile(ctx, "docs/"+lang+"/images", uri)
+	}
+}
+
+// ProductsStatic serves images of product cases.
+func ProductsStatic(ctx *context.Context) {
+	if uri := ctx.Input.Param(":all"); len(uri) > 0 {
+		serveContentFile(ctx, "products/images", uri)
+	}
+}
+
+// serveContentFile writes file of given name under dir of active content snapshot.
+func serveContentFile(ctx *context.Context, dir, name string) {
+	name = path.Clean("/" + name)
+	f, err := os.Open(models.ContentPath(dir + name))
+	if err != nil {
+		ctx.WriteString(err.Error())
+		return
+	}
+	defer f.Close()
+
+	_, err = io.Copy(ctx.ResponseWriter, f)
+	if err != nil {
+		ctx.WriteString(err.Error())
+		return
 	}
 }
@@ -14,10 +14,6 @@
 
 package routers
 
-import (
-	"github.com/beego/beeweb/models"
-)
-
 // DonateRouter serves Donate page.
 type DonateRouter struct {
 	baseRouter
@@ -28,9 +24,6 @@ func (this *DonateRouter) Get() {
 	this.Data["IsDonate"] = true
 	this.TplName = "donate.html"
 
-	// Get language.
-	df := models.GetDoc("donate", this.Lang)
-	this.Data["Title"] = df.Title
-	this.Data["Data"] = string(df.Data)
+	this.setPage("donate")
 	this.Data["IsHasMarkdown"] = true
 }
@@ -0,0 +1,42 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"github.com/beego/i18n"
+)
+
+// ErrorRouter serves error pages in language of the visitor.
+type ErrorRouter struct {
+	baseRouter
+}
+
+// Prepare sets language by URL or visitor without redirecting,
+// error pages are shown at the URL that failed.
+func (this *ErrorRouter) Prepare() {
+	this.setProperties()
+
+	lang := this.Ctx.Input.Param(":lang")
+	if !i18n.IsExist(lang) {
+		lang = this.detectLang()
+	}
+	this.setLang(lang)
+}
+
+// Error404 renders page of 404 Not Found.
+func (this *ErrorRouter) Error404() {
+	this.Data["Title"] = "not_found_title"
+	this.TplName = "404.html"
+}
@@ -0,0 +1,54 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"github.com/astaxie/beego"
+
+	"github.com/beego/beeweb/models"
+)
+
+// FeedRouter serves feeds of blog posts and documentation changes.
+type FeedRouter struct {
+	baseRouter
+}
+
+// BlogAtom serves Atom feed of blog posts.
+func (this *FeedRouter) BlogAtom() {
+	data, err := models.BlogAtom(this.Lang, "beego: "+this.Tr("blog"))
+	this.serveFeed(data, err, "application/atom+xml; charset=utf-8")
+}
+
+// BlogRSS serves RSS feed of blog posts.
+func (this *FeedRouter) BlogRSS() {
+	data, err := models.BlogRSS(this.Lang, "beego: "+this.Tr("blog"), this.Tr("app_intro"))
+	this.serveFeed(data, err, "application/rss+xml; charset=utf-8")
+}
+
+// DocChanges serves Atom feed of documents that changed.
+func (this *FeedRouter) DocChanges() {
+	data, err := models.DocChangesAtom(this.Lang, "beego: "+this.Tr("doc_changes"))
+	this.serveFeed(data, err, "application/atom+xml; charset=utf-8")
+}
+
+func (this *FeedRouter) serveFeed(data []byte, err error, contentType string) {
+	if err != nil {
+		beego.Error("Fail to build feed: " + err.Error())
+		this.Abort("500")
+	}
+
+	this.Ctx.Output.Header("Content-Type", contentType)
+	this.Ctx.Output.Body(data)
+}
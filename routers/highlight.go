@@ -0,0 +1,40 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"github.com/astaxie/beego"
+
+	"github.com/beego/beeweb/models"
+)
+
+// HighlightRouter serves stylesheet of highlighted code blocks,
+// generated in memory by style of app.conf.
+type HighlightRouter struct {
+	beego.Controller
+}
+
+// Get implemented Get method for HighlightRouter.
+func (this *HighlightRouter) Get() {
+	css, err := models.HighlightCSS()
+	if err != nil {
+		beego.Error("routers.HighlightRouter.Get ->", err)
+		this.Abort("500")
+	}
+
+	this.Ctx.Output.Header("Content-Type", "text/css; charset=utf-8")
+	this.Ctx.Output.Header("Cache-Control", "public, max-age=86400")
+	this.Ctx.Output.Body(css)
+}
@@ -0,0 +1,41 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/astaxie/beego"
+)
+
+func TestHighlightRouter(t *testing.T) {
+	handler := beego.NewControllerRegister()
+	handler.Add("/highlight.css", &HighlightRouter{})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/highlight.css", nil))
+
+	if w.Code != 200 {
+		t.Fatalf("got %d, want 200", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("Content-Type is %q, want text/css", ct)
+	}
+	if body := w.Body.String(); !strings.Contains(body, ".chroma") || !strings.Contains(body, ".hl") {
+		t.Errorf("stylesheet has no rules of code blocks:\n%s", body)
+	}
+}
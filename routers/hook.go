@@ -0,0 +1,102 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/astaxie/beego"
+
+	"github.com/beego/beeweb/models"
+)
+
+// HookRouter serves push webhooks of GitHub and Gitea content repositories.
+type HookRouter struct {
+	beego.Controller
+}
+
+// Post implemented Post method for HookRouter.
+func (this *HookRouter) Post() {
+	secret := beego.AppConfig.String("hooks::secret")
+	if len(secret) == 0 {
+		this.CustomAbort(403, "Webhook secret is not configured")
+	}
+
+	body := this.Ctx.Input.RequestBody
+	sig := this.Ctx.Input.Header("X-Hub-Signature-256")
+	if len(sig) == 0 {
+		sig = this.Ctx.Input.Header("X-Gitea-Signature")
+	}
+	if !verifySignature(secret, body, sig) {
+		this.CustomAbort(401, "Invalid signature")
+	}
+
+	event := this.Ctx.Input.Header("X-GitHub-Event")
+	if len(event) == 0 {
+		event = this.Ctx.Input.Header("X-Gitea-Event")
+	}
+	switch event {
+	case "ping":
+		this.Ctx.WriteString("pong")
+		return
+	case "push":
+	default:
+		this.CustomAbort(202, "Event ignored: "+event)
+	}
+
+	var payload struct {
+		Ref        string
+		Repository struct {
+			FullName string `json:"full_name"`
+			CloneURL string `json:"clone_url"`
+			SSHURL   string `json:"ssh_url"`
+			HTMLURL  string `json:"html_url"`
+		}
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		this.CustomAbort(400, "Invalid payload: "+err.Error())
+	}
+
+	repo := payload.Repository
+	names := models.SectionsForPush([]string{repo.CloneURL, repo.SSHURL, repo.HTMLURL}, payload.Ref)
+	if len(names) == 0 {
+		this.CustomAbort(202, "No section is mirrored from "+repo.FullName+" "+payload.Ref)
+	}
+
+	for _, name := range names {
+		models.ScheduleUpdate(name)
+	}
+	beego.Info("Webhook scheduled update of", names)
+
+	this.Ctx.Output.SetStatus(202)
+	this.Ctx.WriteString("Scheduled update of " + strings.Join(names, ", "))
+}
+
+// verifySignature checks HMAC-SHA256 signature of body, in the form of
+// "sha256=<hex>" sent by GitHub or plain hex sent by Gitea.
+func verifySignature(secret string, body []byte, sig string) bool {
+	got, err := hex.DecodeString(strings.TrimPrefix(sig, "sha256="))
+	if err != nil || len(got) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
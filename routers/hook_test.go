@@ -0,0 +1,99 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/astaxie/beego"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestHookRouter(t *testing.T) {
+	beego.BConfig.CopyRequestBody = true
+	handler := beego.NewControllerRegister()
+	handler.Add("/hooks/content", &HookRouter{})
+
+	const secret = "s3cret"
+	push := `{"ref":"refs/heads/master","repository":{"full_name":"someone/else","clone_url":"https://github.com/someone/else.git"}}`
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  map[string]string
+		body    string
+		status  int
+		message string
+	}{
+		{"no secret configured", "", map[string]string{"X-Hub-Signature-256": "sha256=" + sign("", "{}"), "X-GitHub-Event": "ping"}, "{}", 403, "not configured"},
+		{"no signature", secret, map[string]string{"X-GitHub-Event": "ping"}, "{}", 401, "Invalid signature"},
+		{"github signature", secret, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(secret, "{}"), "X-GitHub-Event": "ping"}, "{}", 200, "pong"},
+		{"gitea signature", secret, map[string]string{"X-Gitea-Signature": sign(secret, "{}"), "X-Gitea-Event": "ping"}, "{}", 200, "pong"},
+		{"signature of other secret", secret, map[string]string{"X-Hub-Signature-256": "sha256=" + sign("other", "{}"), "X-GitHub-Event": "ping"}, "{}", 401, "Invalid signature"},
+		{"signature of other body", secret, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(secret, "{ }"), "X-GitHub-Event": "ping"}, "{}", 401, "Invalid signature"},
+		{"malformed signature", secret, map[string]string{"X-Hub-Signature-256": "sha256=xyz", "X-GitHub-Event": "ping"}, "{}", 401, "Invalid signature"},
+		{"sha1 signature", secret, map[string]string{"X-Hub-Signature-256": "sha1=" + sign(secret, "{}"), "X-GitHub-Event": "ping"}, "{}", 401, "Invalid signature"},
+		{"other event", secret, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(secret, "{}"), "X-GitHub-Event": "issues"}, "{}", 202, "Event ignored"},
+		{"invalid payload", secret, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(secret, "{"), "X-GitHub-Event": "push"}, "{", 400, "Invalid payload"},
+		{"push of other repository", secret, map[string]string{"X-Gitea-Signature": sign(secret, push), "X-Gitea-Event": "push"}, push, 202, "No section"},
+	}
+	for _, test := range tests {
+		beego.AppConfig.Set("hooks::secret", test.secret)
+
+		req := httptest.NewRequest("POST", "/hooks/content", strings.NewReader(test.body))
+		for k, v := range test.header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != test.status || !strings.Contains(w.Body.String(), test.message) {
+			t.Errorf("%s: got %d %q, want %d %q", test.name, w.Code, w.Body.String(), test.status, test.message)
+		}
+	}
+	beego.AppConfig.Set("hooks::secret", "")
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/master"}`)
+	tests := []struct {
+		sig  string
+		want bool
+	}{
+		{"sha256=" + sign("key", string(body)), true},
+		{sign("key", string(body)), true},
+		{"sha256=" + strings.ToUpper(sign("key", string(body))), true},
+		{"sha256=" + sign("other", string(body)), false},
+		{"sha256=" + sign("key", string(body))[:32], false},
+		{"", false},
+		{"sha256=", false},
+		{"not hex", false},
+	}
+	for _, test := range tests {
+		if got := verifySignature("key", body, test.sig); got != test.want {
+			t.Errorf("verifySignature(%q) = %v, want %v", test.sig, got, test.want)
+		}
+	}
+}
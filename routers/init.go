@@ -2,6 +2,7 @@ package routers
 
 import (
 	"errors"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -11,6 +12,8 @@ import (
 	"github.com/astaxie/beego"
 	"github.com/beego/compress"
 	"github.com/beego/i18n"
+
+	"github.com/beego/beeweb/models"
 )
 
 var (
@@ -108,6 +111,13 @@ func InitApp() {
 						settingCompress()
 						beego.Info("Beego Compress Reloaded")
 					}
+
+				default:
+					if event.Name == models.CurrentSnapshotFile {
+						if err := models.ReloadSnapshot(); err != nil {
+							beego.Error(err)
+						}
+					}
 				}
 			}
 		}
@@ -116,4 +126,11 @@ func InitApp() {
 	if err := watcher.WatchFlags("conf", fsnotify.FSN_MODIFY); err != nil {
 		beego.Error(err)
 	}
+
+	// Content snapshot is switched by renaming a new pointer file over the old one.
+	contentDir := filepath.Dir(models.CurrentSnapshotFile)
+	os.MkdirAll(contentDir, os.ModePerm)
+	if err := watcher.WatchFlags(contentDir, fsnotify.FSN_CREATE|fsnotify.FSN_MODIFY); err != nil {
+		beego.Error(err)
+	}
 }
@@ -0,0 +1,126 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// langAliases maps language tags, lower-cased, to the regional ones
+// they are usually written in.
+var langAliases = map[string]string{
+	"zh-hans": "zh-cn",
+	"zh-sg":   "zh-cn",
+	"zh-hant": "zh-tw",
+	"zh-hk":   "zh-tw",
+	"zh-mo":   "zh-tw",
+}
+
+// langRange is a language range of 'Accept-Language' with its quality value.
+type langRange struct {
+	tag string
+	q   float64
+}
+
+// parseAcceptLanguage parses 'Accept-Language' of RFC 7231, ranges are
+// lower-cased and ordered by quality, those of quality 0 are dropped.
+func parseAcceptLanguage(header string) []langRange {
+	var ranges []langRange
+	for _, v := range strings.Split(header, ",") {
+		params := strings.Split(v, ";")
+		tag := strings.ToLower(strings.TrimSpace(params[0]))
+		if len(tag) == 0 {
+			continue
+		}
+
+		q := 1.0
+		for _, p := range params[1:] {
+			// Parameter names are case-insensitive.
+			p = strings.TrimSpace(p)
+			if !strings.HasPrefix(strings.ToLower(p), "q=") {
+				continue
+			}
+			var err error
+			if q, err = strconv.ParseFloat(p[2:], 64); err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+		}
+		if q == 0 {
+			continue
+		}
+		ranges = append(ranges, langRange{tag: strings.Replace(tag, "_", "-", -1), q: q})
+	}
+
+	// Ranges of the same quality keep their order.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
+
+// matchLang returns language of langs that best matches 'Accept-Language',
+// or an empty string when none does.
+func matchLang(header string, langs []string) string {
+	for _, r := range parseAcceptLanguage(header) {
+		if r.tag == "*" {
+			continue
+		}
+		if lang := matchTag(r.tag, langs); len(lang) > 0 {
+			return lang
+		}
+	}
+	return ""
+}
+
+// matchTag matches tag by BCP 47 lookup: the tag, or its alias, is
+// truncated subtag by subtag until one of langs equals it; failing that,
+// any of langs of the same primary language matches.
+func matchTag(tag string, langs []string) string {
+	for t := tag; len(t) > 0; {
+		if lang := findLang(t, langs); len(lang) > 0 {
+			return lang
+		}
+		if alias, ok := langAliases[t]; ok {
+			if lang := findLang(alias, langs); len(lang) > 0 {
+				return lang
+			}
+		}
+
+		i := strings.LastIndex(t, "-")
+		if i == -1 {
+			break
+		}
+		t = t[:i]
+	}
+
+	primary := strings.SplitN(tag, "-", 2)[0]
+	for _, lang := range langs {
+		if strings.EqualFold(strings.SplitN(lang, "-", 2)[0], primary) {
+			return lang
+		}
+	}
+	return ""
+}
+
+func findLang(tag string, langs []string) string {
+	for _, lang := range langs {
+		if strings.EqualFold(lang, tag) {
+			return lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"testing"
+)
+
+func TestMatchLang(t *testing.T) {
+	langs := []string{"en-US", "zh-CN", "zh-TW", "ru-RU"}
+
+	// An empty result makes detectLang fall back to the default language.
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{"ru,en;q=0.8", "ru-RU"},
+		{"ru;q=0.5,en;q=0.8", "en-US"},
+		{"zh-Hans-CN", "zh-CN"},
+		{"zh-Hant", "zh-TW"},
+		{"zh-HK,en;q=0.9", "zh-TW"},
+		{"zh_CN", "zh-CN"},
+		{"zh-TW;q=0,en;q=0.5", "en-US"},
+		{"zh-TW;q=0", ""},
+		{"*", ""},
+		{"fr,*;q=0.5", ""},
+		{"*,zh-CN;q=0.5", "zh-CN"},
+		{"ru;q=abc,en;q=0.5", "en-US"},
+		{"ru;q=2,en;q=0.5", "en-US"},
+		{"ru;Q=0.9,en;q=0.5", "ru-RU"},
+		{"en;Q=0,ru;q=0.5", "ru-RU"},
+		{"ru;q=0.8,en;q=0.8", "ru-RU"},
+		{"en;q=0.8,ru;q=0.8", "en-US"},
+		{"en-GB", "en-US"},
+		{"ru-UA;q=0.9", "ru-RU"},
+		{"fr,de;q=0.8,ja", ""},
+	}
+	for _, test := range tests {
+		if got := matchLang(test.header, langs); got != test.want {
+			t.Errorf("matchLang(%q) = %q, want %q", test.header, got, test.want)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []langRange
+	}{
+		{"", nil},
+		{"ru,en;q=0.8", []langRange{{"ru", 1}, {"en", 0.8}}},
+		{" en-US ; q=0.5 , zh-Hans-CN", []langRange{{"zh-hans-cn", 1}, {"en-us", 0.5}}},
+		{"zh-TW;q=0,ru", []langRange{{"ru", 1}}},
+		{"en;Q=0.3,ru;q=0.3", []langRange{{"en", 0.3}, {"ru", 0.3}}},
+		{"en;q=,ru;q=-1,ja;q=1.5", nil},
+		{"*;q=0.1", []langRange{{"*", 0.1}}},
+	}
+	for _, test := range tests {
+		got := parseAcceptLanguage(test.header)
+		if len(got) != len(test.want) {
+			t.Errorf("parseAcceptLanguage(%q) = %v, want %v", test.header, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("parseAcceptLanguage(%q) = %v, want %v", test.header, got, test.want)
+				break
+			}
+		}
+	}
+}
@@ -1,9 +1,5 @@
 package routers
 
-import (
-	"github.com/beego/beeweb/models"
-)
-
 type PageRouter struct {
 	baseRouter
 }
@@ -12,10 +8,7 @@ func (this *PageRouter) Get() {
 	this.Data["IsTeam"] = true
 	this.TplName = "team.html"
 
-	// Get language.
-	df := models.GetDoc("team", this.Lang)
-	this.Data["Title"] = df.Title
-	this.Data["Data"] = string(df.Data)
+	this.setPage("team")
 }
 
 type AboutRouter struct {
@@ -26,8 +19,5 @@ func (this *AboutRouter) Get() {
 	this.Data["IsAbout"] = true
 	this.TplName = "about.html"
 
-	// Get language.
-	df := models.GetDoc("about", this.Lang)
-	this.Data["Title"] = df.Title
-	this.Data["Data"] = string(df.Data)
+	this.setPage("about")
 }
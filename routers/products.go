@@ -11,5 +11,5 @@ type ProductsRouter struct {
 func (this *ProductsRouter) Get() {
 	this.TplName = "products.html"
 	this.Data["IsProducts"] = true
-	this.Data["Products"] = models.Products
+	this.Data["Products"] = models.GetProducts()
 }
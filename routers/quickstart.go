@@ -14,10 +14,6 @@
 
 package routers
 
-import (
-	"github.com/beego/beeweb/models"
-)
-
 // QuickStartRouter serves about page.
 type QuickStartRouter struct {
 	baseRouter
@@ -28,9 +24,6 @@ func (this *QuickStartRouter) Get() {
 	this.Data["IsQuickStart"] = true
 	this.TplName = "quickstart.html"
 
-	df := models.GetDoc("quickstart", this.Lang)
-	this.Data["Section"] = "quickstart"
-	this.Data["Title"] = df.Title
-	this.Data["Data"] = string(df.Data)
+	this.setPage("quickstart")
 	this.Data["IsHasMarkdown"] = true
 }
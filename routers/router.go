@@ -16,11 +16,14 @@
 package routers
 
 import (
+	"path"
 	"strings"
 	"time"
 
 	"github.com/astaxie/beego"
 	"github.com/beego/i18n"
+
+	"github.com/beego/beeweb/models"
 )
 
 var (
@@ -30,6 +33,9 @@ var (
 
 var langTypes []*langType // Languages are supported.
 
+// pagePaths are patterns of pages registered without parameters, in order.
+var pagePaths []string
+
 // langType represents a language type.
 type langType struct {
 	Lang, Name string
@@ -41,69 +47,111 @@ type baseRouter struct {
 	i18n.Locale
 }
 
+// Register registers controller at pattern under prefix of every language,
+// e.g. "/docs/*" at "/:lang/docs/*", and at pattern itself to redirect
+// URLs of older versions that have no language to prefixed ones.
+func Register(pattern string, c beego.ControllerInterface, mappingMethods ...string) {
+	beego.Router("/:lang"+pattern, c, mappingMethods...)
+	beego.Router(pattern, c, mappingMethods...)
+
+	// Feeds and other files have extensions.
+	if !strings.ContainsAny(pattern, ":*") && len(path.Ext(pattern)) == 0 {
+		pagePaths = append(pagePaths, pattern)
+	}
+}
+
 // Prepare implemented Prepare method for baseRouter.
 func (this *baseRouter) Prepare() {
-	// Setting properties.
-	this.Data["AppVer"] = AppVer
-	this.Data["IsPro"] = IsPro
+	this.setProperties()
 
-	this.Data["PageStartTime"] = time.Now()
-
-	// Redirect to make URL clean.
-	if this.setLangVer() {
-		i := strings.Index(this.Ctx.Request.RequestURI, "?")
-		this.Redirect(this.Ctx.Request.RequestURI[:i], 302)
+	lang := this.Ctx.Input.Param(":lang")
+	if len(lang) == 0 {
+		this.redirectLang()
 		return
 	}
+	if !i18n.IsExist(lang) {
+		this.Abort("404")
+	}
+
+	this.setLang(lang)
+	this.Data["LangPath"] = this.langPath()
+	this.Data["LangTypes"] = langTypes
+	this.Data["SiteURL"] = models.BaseURL()
+	this.initSEO()
 }
 
-// setLangVer sets site language version.
-func (this *baseRouter) setLangVer() bool {
-	isNeedRedir := false
-	hasCookie := false
+func (this *baseRouter) setProperties() {
+	this.Data["AppVer"] = AppVer
+	this.Data["IsPro"] = IsPro
+
+	this.Data["PageStartTime"] = time.Now()
+}
 
+// detectLang returns language of visitor for URLs without language,
+// by URL arguments, cookies or 'Accept-Language' in order.
+func (this *baseRouter) detectLang() string {
 	// 1. Check URL arguments.
 	lang := this.Input().Get("lang")
 
 	// 2. Get language information from cookies.
-	if len(lang) == 0 {
-		lang = this.Ctx.GetCookie("lang")
-		hasCookie = true
-	} else {
-		isNeedRedir = true
-	}
-
-	// Check again in case someone modify by purpose.
 	if !i18n.IsExist(lang) {
-		lang = ""
-		isNeedRedir = false
-		hasCookie = false
+		lang = this.Ctx.GetCookie("lang")
 	}
 
 	// 3. Get language information from 'Accept-Language'.
-	if len(lang) == 0 {
-		al := this.Ctx.Request.Header.Get("Accept-Language")
-		if len(al) > 4 {
-			al = al[:5] // Only compare first 5 letters.
-			if i18n.IsExist(al) {
-				lang = al
-			}
+	if !i18n.IsExist(lang) {
+		langs := make([]string, 0, len(langTypes))
+		for _, v := range langTypes {
+			langs = append(langs, v.Lang)
 		}
+		lang = matchLang(this.Ctx.Request.Header.Get("Accept-Language"), langs)
 	}
 
 	// 4. Default language is English.
 	if len(lang) == 0 {
-		lang = "en-US"
-		isNeedRedir = false
+		lang = models.DefaultLang
 	}
+	return lang
+}
 
-	curLang := langType{
-		Lang: lang,
+// redirectLang redirects URL without language to the one of visitor's language.
+func (this *baseRouter) redirectLang() {
+	u := *this.Ctx.Request.URL
+	q := u.Query()
+	lang := this.detectLang()
+	q.Del("lang")
+	u.RawQuery = q.Encode()
+	u.Path = "/" + lang + u.Path
+
+	// The target depends on who asks.
+	this.Ctx.Output.Header("Vary", "Accept-Language, Cookie")
+	this.Redirect(u.String(), 302)
+	this.StopRun()
+}
+
+// langPath returns path of request without language prefix.
+func (this *baseRouter) langPath() string {
+	p := strings.TrimPrefix(this.Ctx.Request.URL.Path, "/"+this.Lang)
+	if len(p) == 0 {
+		return "/"
+	}
+	return p
+}
+
+// langURL returns given path under prefix of current language.
+func (this *baseRouter) langURL(p string) string {
+	return "/" + this.Lang + p
+}
+
+// setLang sets site language version.
+func (this *baseRouter) setLang(lang string) {
+	// Remember language for URLs without one.
+	if this.Ctx.GetCookie("lang") != lang {
+		this.Ctx.SetCookie("lang", lang, 1<<31-1, "/")
 	}
 
-	// Save language information in cookies.
-	if !hasCookie {
-		this.Ctx.SetCookie("lang", curLang.Lang, 1<<31-1, "/")
+	curLang := langType{
+		Lang: lang,
 	}
 
 	restLangs := make([]*langType, 0, len(langTypes)-1)
@@ -120,6 +168,20 @@ func (this *baseRouter) setLangVer() bool {
 	this.Data["Lang"] = curLang.Lang
 	this.Data["CurLang"] = curLang.Name
 	this.Data["RestLangs"] = restLangs
+}
+
+// setPage sets title and content of documentation page by given name,
+// or responds with 404 when no version of the page exists.
+func (this *baseRouter) setPage(name string) {
+	page, _ := models.FindPage(name, this.Lang)
+	if page == nil {
+		this.Abort("404")
+	}
 
-	return isNeedRedir
+	this.Data["Section"] = name
+	this.Data["Title"] = page.Name
+	r := page.Render()
+	this.Data["Data"] = string(r.HTML)
+	this.Data["TOC"] = r.Headings
+	this.setDocSEO(page, "", "")
 }
@@ -0,0 +1,94 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/astaxie/beego"
+	"github.com/beego/i18n"
+
+	"github.com/beego/beeweb/models"
+)
+
+type echoRouter struct {
+	baseRouter
+}
+
+func (this *echoRouter) Get() {
+	this.Ctx.WriteString(fmt.Sprint(this.Lang, " ", this.Data["LangPath"], " ", this.Data["SiteURL"]))
+}
+
+func initTestLocales(t *testing.T) {
+	langTypes = nil
+	for _, lang := range []string{"en-US", "zh-CN", "ru-RU"} {
+		langTypes = append(langTypes, &langType{Lang: lang, Name: lang})
+		if err := i18n.SetMessage(lang, "../conf/locale_"+lang+".ini"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRedirectLang(t *testing.T) {
+	initTestLocales(t)
+	Register("/echo/*", &echoRouter{})
+
+	tests := []struct {
+		url, cookie, accept string
+		status              int
+		location, body      string
+	}{
+		{"/echo/intro", "", "ru,en;q=0.8", 302, "/ru-RU/echo/intro", ""},
+		{"/echo/intro", "", "zh-Hans-CN", 302, "/zh-CN/echo/intro", ""},
+		{"/echo/intro", "", "fr,de;q=0.5", 302, "/" + models.DefaultLang + "/echo/intro", ""},
+		{"/echo/intro", "", "", 302, "/" + models.DefaultLang + "/echo/intro", ""},
+		{"/echo/intro", "lang=zh-CN", "ru", 302, "/zh-CN/echo/intro", ""},
+		{"/echo/intro", "lang=xx-XX", "ru", 302, "/ru-RU/echo/intro", ""},
+		{"/echo/intro?lang=ru-RU&page=2", "lang=zh-CN", "en", 302, "/ru-RU/echo/intro?page=2", ""},
+		{"/echo/intro?lang=xx&page=2", "", "zh-CN", 302, "/zh-CN/echo/intro?page=2", ""},
+		{"/zh-CN/echo/intro", "lang=ru-RU", "ru", 200, "", "zh-CN /echo/intro " + models.BaseURL()},
+		{"/xx-XX/echo/intro", "", "", 404, "", ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.url, nil)
+		req.Host = "mirror.example.com"
+		if len(test.cookie) > 0 {
+			req.Header.Set("Cookie", test.cookie)
+		}
+		if len(test.accept) > 0 {
+			req.Header.Set("Accept-Language", test.accept)
+		}
+		w := httptest.NewRecorder()
+		beego.BeeApp.Handlers.ServeHTTP(w, req)
+
+		if w.Code != test.status {
+			t.Errorf("GET %s: status %d, want %d", test.url, w.Code, test.status)
+			continue
+		}
+		if test.status == 302 {
+			if loc := w.Header().Get("Location"); loc != test.location {
+				t.Errorf("GET %s: redirected to %s, want %s", test.url, loc, test.location)
+			}
+			if vary := w.Header().Get("Vary"); vary != "Accept-Language, Cookie" {
+				t.Errorf("GET %s: Vary is %q", test.url, vary)
+			}
+		}
+		if test.status == 200 && w.Body.String() != test.body {
+			t.Errorf("GET %s: body %q, want %q", test.url, w.Body.String(), test.body)
+		}
+	}
+}
@@ -44,14 +44,14 @@ func (this *SamplesRouter) Get() {
 
 	// Get language.
 	curLang, _ := this.Data["LangVer"].(langType)
-	df := models.GetDoc(sec, curLang.Lang)
-	if df == nil {
+	page := models.GetPage("docs", sec, curLang.Lang)
+	if page == nil {
 		this.Redirect("/samples/Samples_Introduction", 302)
 		return
 	}
 
-	this.Data["Title"] = df.Title
-	this.Data["Data"] = string(df.Data)
+	this.Data["Title"] = page.Name
+	this.Data["Data"] = page.GetContent()
 	this.Data["IsHasMarkdown"] = true
 	this.TplName = "samples_" + curLang.Lang + ".html"
 }
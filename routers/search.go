@@ -0,0 +1,101 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"strings"
+
+	"github.com/astaxie/beego"
+	"github.com/beego/i18n"
+
+	"github.com/beego/beeweb/models"
+)
+
+const (
+	searchPageLimit = 50
+	searchAPILimit  = 20
+	searchMaxLimit  = 100
+)
+
+// SearchRouter serves search page.
+type SearchRouter struct {
+	baseRouter
+}
+
+// Get implemented Get method for SearchRouter.
+func (this *SearchRouter) Get() {
+	this.Data["IsSearch"] = true
+	this.TplName = "search.html"
+
+	q := strings.TrimSpace(this.GetString("q"))
+	locale := searchLocale(this.GetString("locale"), this.Lang)
+
+	this.Data["Title"] = "search"
+	this.Data["Query"] = q
+	this.Data["Locale"] = locale
+	if len(q) == 0 {
+		return
+	}
+
+	results, total := models.Search(q, locale, searchPageLimit, 0)
+	this.Data["Results"] = results
+	this.Data["Total"] = total
+}
+
+// SearchAPIRouter serves search results in JSON format.
+type SearchAPIRouter struct {
+	beego.Controller
+}
+
+// Get implemented Get method for SearchAPIRouter.
+func (this *SearchAPIRouter) Get() {
+	q := strings.TrimSpace(this.GetString("q"))
+	locale := searchLocale(this.GetString("locale"), "")
+
+	limit, _ := this.GetInt("limit", searchAPILimit)
+	if limit <= 0 || limit > searchMaxLimit {
+		limit = searchAPILimit
+	}
+	offset, _ := this.GetInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	results, total := models.Search(q, locale, limit, offset)
+	if results == nil {
+		results = []*models.SearchResult{}
+	}
+
+	this.Data["json"] = map[string]interface{}{
+		"query":   q,
+		"locale":  locale,
+		"total":   total,
+		"offset":  offset,
+		"results": results,
+	}
+	this.ServeJSON()
+}
+
+// searchLocale returns language to filter search results by,
+// an empty string means all languages.
+func searchLocale(locale, def string) string {
+	switch {
+	case locale == "all":
+		return ""
+	case i18n.IsExist(locale):
+		return locale
+	}
+	return def
+}
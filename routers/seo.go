@@ -0,0 +1,216 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+
+	"github.com/beego/beeweb/models"
+)
+
+// SEO is metadata of a page for search engines and link previews,
+// rendered by views/base/head.html as Open Graph, Twitter Card and JSON-LD.
+type SEO struct {
+	Title       string
+	Description string
+	Canonical   string
+	Image       string
+	Locale      string
+	Type        string // "website", or "article" for documents and blog posts.
+	Schema      string // JSON-LD type of article, TechArticle or BlogPosting.
+	Published   time.Time
+	Modified    time.Time
+	Authors     []string
+	Tags        []string
+	Breadcrumbs []*Breadcrumb
+}
+
+// Breadcrumb is a level of the path to a page.
+type Breadcrumb struct {
+	Name, URL string
+}
+
+// TwitterCard returns type of Twitter Card, a large image for pages having their own.
+func (s *SEO) TwitterCard() string {
+	if s.Image != defaultImage() {
+		return "summary_large_image"
+	}
+	return "summary"
+}
+
+// JSONLD returns structured data of the page.
+func (s *SEO) JSONLD() template.JS {
+	var items []interface{}
+	if len(s.Schema) > 0 {
+		article := map[string]interface{}{
+			"@context":         "https://schema.org",
+			"@type":            s.Schema,
+			"headline":         s.Title,
+			"description":      s.Description,
+			"url":              s.Canonical,
+			"mainEntityOfPage": s.Canonical,
+			"image":            s.Image,
+			"inLanguage":       strings.Replace(s.Locale, "_", "-", 1),
+			"publisher": map[string]interface{}{
+				"@type": "Organization",
+				"name":  "beego",
+				"logo":  map[string]string{"@type": "ImageObject", "url": defaultImage()},
+			},
+		}
+		if !s.Published.IsZero() {
+			article["datePublished"] = s.Published.Format(time.RFC3339)
+		}
+		if !s.Modified.IsZero() {
+			article["dateModified"] = s.Modified.Format(time.RFC3339)
+		}
+		if len(s.Authors) > 0 {
+			authors := make([]map[string]string, 0, len(s.Authors))
+			for _, name := range s.Authors {
+				authors = append(authors, map[string]string{"@type": "Person", "name": name})
+			}
+			article["author"] = authors
+		}
+		if len(s.Tags) > 0 {
+			article["keywords"] = strings.Join(s.Tags, ", ")
+		}
+		items = append(items, article)
+	}
+
+	if len(s.Breadcrumbs) > 0 {
+		list := make([]map[string]interface{}, 0, len(s.Breadcrumbs))
+		for i, b := range s.Breadcrumbs {
+			list = append(list, map[string]interface{}{
+				"@type":    "ListItem",
+				"position": i + 1,
+				"name":     b.Name,
+				"item":     b.URL,
+			})
+		}
+		items = append(items, map[string]interface{}{
+			"@context":        "https://schema.org",
+			"@type":           "BreadcrumbList",
+			"itemListElement": list,
+		})
+	}
+
+	if len(items) == 0 {
+		return ""
+	}
+	// Characters such as '<' are escaped, so it is safe in script element.
+	data, err := json.Marshal(items)
+	if err != nil {
+		beego.Error("Fail to encode JSON-LD: " + err.Error())
+		return ""
+	}
+	return template.JS(data)
+}
+
+// defaultImage returns absolute URL of image of pages without their own, by "seo::image".
+func defaultImage() string {
+	return absURL(beego.AppConfig.DefaultString("seo::image", "/static/img/beego_purple.png"), models.BaseURL()+"/")
+}
+
+// absURL resolves ref against base.
+func absURL(ref, base string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// initSEO sets metadata of the page that routers refine by what they serve.
+func (this *baseRouter) initSEO() {
+	this.Data["SEO"] = &SEO{
+		Title:       "beego: " + this.Tr("app_intro"),
+		Description: this.Tr("app_intro"),
+		Canonical:   models.BaseURL() + (&url.URL{Path: this.langURL(this.langPath())}).EscapedPath(),
+		Image:       defaultImage(),
+		Locale:      strings.Replace(this.Lang, "-", "_", 1),
+		Type:        "website",
+	}
+}
+
+// seo returns metadata of the page.
+func (this *baseRouter) seo() *SEO {
+	s, ok := this.Data["SEO"].(*SEO)
+	if !ok {
+		s = new(SEO)
+		this.Data["SEO"] = s
+	}
+	return s
+}
+
+// articleSchemas maps sections of documents to JSON-LD types of their articles.
+var articleSchemas = map[string]string{
+	"docs": "TechArticle",
+	"blog": "BlogPosting",
+}
+
+// setDocSEO sets metadata of the page by document it shows at path p, or
+// at path of request when p is empty, as an article when section is "docs"
+// or "blog". Untranslated documents name the one of language they are taken
+// from as canonical.
+func (this *baseRouter) setDocSEO(doc *models.DocNode, section, p string, crumbs ...*Breadcrumb) {
+	s := this.seo()
+	if len(p) > 0 {
+		s.Canonical = this.crumb("", p).URL
+	}
+	s.Title = doc.Name
+	s.Description = doc.Description
+	if len(s.Description) == 0 {
+		s.Description = doc.Abstract
+	}
+	if len(s.Description) == 0 {
+		s.Description = doc.Lead()
+	}
+	if len(s.Description) == 0 {
+		s.Description = doc.Summary
+	}
+	if len(doc.Fallback) > 0 {
+		s.Canonical = models.BaseURL() + "/" + doc.Fallback + strings.TrimPrefix(s.Canonical, models.BaseURL()+"/"+this.Lang)
+	}
+	if image, ok := doc.Extra["image"].(string); ok && len(image) > 0 {
+		s.Image = absURL(image, s.Canonical)
+	}
+
+	if schema, ok := articleSchemas[section]; ok {
+		s.Type = "article"
+		s.Schema = schema
+		s.Published = doc.Date
+		s.Modified = models.CurrentStore().Updated(section, doc)
+		s.Authors = doc.Authors
+		s.Tags = doc.Tags
+	}
+
+	if len(crumbs) > 0 {
+		s.Breadcrumbs = append(crumbs, &Breadcrumb{Name: doc.Name, URL: s.Canonical})
+	}
+}
+
+// crumb returns breadcrumb of given path under prefix of current language.
+func (this *baseRouter) crumb(name, p string) *Breadcrumb {
+	return &Breadcrumb{Name: name, URL: models.BaseURL() + (&url.URL{Path: this.langURL(p)}).EscapedPath()}
+}
@@ -0,0 +1,90 @@
+// Copyright 2013 Beego Web authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package routers
+
+import (
+	"strings"
+
+	"github.com/astaxie/beego"
+	"github.com/beego/i18n"
+
+	"github.com/beego/beeweb/models"
+)
+
+// SitemapRouter serves sitemaps and robots.txt.
+type SitemapRouter struct {
+	beego.Controller
+}
+
+// sitemapPaths returns paths of registered pages except ones of "sitemap::exclude".
+func sitemapPaths() []string {
+	exclude := strings.Split(beego.AppConfig.String("sitemap::exclude"), "|")
+
+	paths := make([]string, 0, len(pagePaths))
+	for _, p := range pagePaths {
+		if !isExcluded(p, exclude) {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+func isExcluded(p string, exclude []string) bool {
+	for _, v := range exclude {
+		if v = strings.TrimSpace(v); len(v) > 0 && v == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Index serves sitemap of the site, or index of sitemaps of languages when it is large.
+func (this *SitemapRouter) Index() {
+	data, err := models.Sitemap(sitemapPaths())
+	this.serveSitemap(data, err)
+}
+
+// Lang serves sitemap of a language, "?page=" selects part of a large one.
+func (this *SitemapRouter) Lang() {
+	lang := this.Ctx.Input.Param(":lang")
+	if !i18n.IsExist(lang) {
+		this.Abort("404")
+	}
+
+	paths := sitemapPaths()
+	page, _ := this.GetInt("page", 1)
+	if page < 1 || page > models.SitemapPages(lang, paths) {
+		this.Abort("404")
+	}
+
+	data, err := models.LangSitemap(lang, paths, page)
+	this.serveSitemap(data, err)
+}
+
+// Robots serves robots.txt.
+func (this *SitemapRouter) Robots() {
+	this.Ctx.Output.Header("Content-Type", "text/plain; charset=utf-8")
+	this.Ctx.Output.Body([]byte(models.Robots()))
+}
+
+func (this *SitemapRouter) serveSitemap(data []byte, err error) {
+	if err != nil {
+		beego.Error("Fail to build sitemap: " + err.Error())
+		this.Abort("500")
+	}
+
+	this.Ctx.Output.Header("Content-Type", "application/xml; charset=utf-8")
+	this.Ctx.Output.Body(data)
+}
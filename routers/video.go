@@ -14,8 +14,6 @@
 
 package routers
 
-import "github.com/beego/beeweb/models"
-
 // HomeRouter serves home page.
 type VideoRouter struct {
 	baseRouter
@@ -26,8 +24,6 @@ func (this *VideoRouter) Get() {
 	this.Data["IsVideo"] = true
 	this.TplName = "video.html"
 
-	df := models.GetDoc("screencasts", this.Lang)
-	this.Data["Section"] = "screencasts"
-	this.Data["Data"] = string(df.Data)
+	this.setPage("screencasts")
 	this.Data["IsHasMarkdown"] = true
 }
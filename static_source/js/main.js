@@ -17,12 +17,11 @@
 		$e.blur();
 	});
 
-	// change locale and reload page
+	// remember locale, the link opens the page in it
 	$(document).on('click', '.lang-changed', function(){
 		var $e = $(this);
 		var lang = $e.data('lang');
 		$.cookie('lang', lang, {path: '/', expires: 365});
-		window.location.reload();
 	});
 
 	(function(){
@@ -45,7 +44,8 @@
 				$img.addClass('img-responsive');
 			});
 
-			var $pre = $e.find('pre > code').parent();
+			// Code blocks highlighted by server are skipped.
+			var $pre = $e.find('pre > code').parent().not('.chroma');
 			$pre.addClass("prettyprint");
 			prettyPrint();
 		};
@@ -170,7 +170,13 @@
             if (node.hasClass('ui')) {
                 return;
             }
-            var val = encodeURIComponent(node.text().toLowerCase().replace(/\s+/g, "-"));
+            var val = node.attr('id');
+            if (val) {
+                // Generated by server, moved to the wrapper.
+                node.removeAttr('id');
+            } else {
+                val = encodeURIComponent(node.text().toLowerCase().replace(/\s+/g, "-"));
+            }
             node = node.wrap('<div id="' + val + '" class="anchor-wrap" ></div>');
             node.append('<a class="anchor" href="#' + val + '"><span class="octicon octicon-link"></span></a>');
         });